@@ -0,0 +1,163 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// escapeRegexp matches escape sequences in input: CSI(e.g. SGR "\033[31m"), OSC terminated by BEL or ST, and other 2 bytes sequences
+var escapeRegexp = regexp.MustCompile("\033(\\[[0-?]*[ -/]*[@-~]|\\][^\007\033]*(\007|\033\\\\)|[ -/]*[0-~])")
+
+// sgrRegexp matches SGR sequence which changes colors and styles
+var sgrRegexp = regexp.MustCompile("^\033\\[[0-9;:]*m$")
+
+// stripANSI removes escape sequences from text
+func stripANSI(text string) string {
+	if !strings.Contains(text, "\033") {
+		return text
+	}
+	return escapeRegexp.ReplaceAllString(text, "")
+}
+
+// visibleText returns text without escape sequences and byte offsets in text of each byte of it.
+// offsets has one more element which is length of text.
+func visibleText(text string) (string, []int) {
+	var visible strings.Builder
+	offsets := make([]int, 0, len(text)+1)
+	last := 0
+	for _, m := range append(escapeRegexp.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		visible.WriteString(text[last:m[0]])
+		for i := last; i < m[0]; i++ {
+			offsets = append(offsets, i)
+		}
+		last = m[1]
+	}
+	return visible.String(), append(offsets, len(text))
+}
+
+// matchEscaped is matchText for text which has escape sequences. regexps are matched with visible text,
+// and returned spans are byte offsets of text with escape sequences which are split not to include them.
+func (kolorit *kolorit) matchEscaped(re *regexp.Regexp, reErase *regexp.Regexp, text string) (string, []span, int) {
+	visible, offsets := visibleText(text)
+	if erased := reErase.FindAllStringIndex(visible, -1); len(erased) > 0 {
+		var b strings.Builder
+		last := 0
+		for _, m := range erased {
+			for i := m[0]; i < m[1]; i++ {
+				b.WriteString(text[last:offsets[i]])
+				last = offsets[i] + 1
+			}
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+		visible, offsets = visibleText(text)
+	}
+
+	found, matchedKind := findSpans(re, visible)
+	spans := make([]span, 0, len(found))
+	for _, s := range found {
+		start := s.start
+		for i := s.start + 1; i <= s.end; i++ {
+			if i == s.end || offsets[i] != offsets[i-1]+1 {
+				spans = append(spans, span{start: offsets[start], end: offsets[i-1] + 1, color: s.color})
+				start = i
+			}
+		}
+	}
+	return text, spans, matchedKind
+}
+
+// plainOf removes escape sequences from text and moves spans of it. spans split by escape sequences are joined.
+func plainOf(text string, spans []span) (string, []span) {
+	if !strings.Contains(text, "\033") {
+		return text, spans
+	}
+	visible, offsets := visibleText(text)
+	// position in visible text of each byte of text
+	positions := make([]int, len(text)+1)
+	for i := range positions {
+		positions[i] = -1
+	}
+	for i, o := range offsets {
+		positions[o] = i
+	}
+	for i := len(positions) - 2; i >= 0; i-- {
+		if positions[i] == -1 {
+			positions[i] = positions[i+1]
+		}
+	}
+
+	moved := make([]span, 0, len(spans))
+	for i, s := range spans {
+		m := span{start: positions[s.start], end: positions[s.end], color: s.color}
+		if n := len(moved) - 1; n >= 0 && spans[i-1].end != s.start && moved[n].end == m.start && moved[n].color == m.color {
+			moved[n].end = m.end
+			continue
+		}
+		moved = append(moved, m)
+	}
+	return visible, moved
+}
+
+// sgrState is parameters of SGR sequences in effect since the last reset
+type sgrState []string
+
+// apply returns state changed by seq. seq other than SGR doesn't change it.
+func (state sgrState) apply(seq string) sgrState {
+	if !sgrRegexp.MatchString(seq) {
+		return state
+	}
+	params := strings.Split(seq[2:len(seq)-1], ";")
+	for i := 0; i < len(params); i++ {
+		switch params[i] {
+		case "", "0":
+			state = nil
+		case "38", "48", "58":
+			// extended color: 5;N or 2;R;G;B
+			n := i + 1
+			if n < len(params) && params[n] == "5" {
+				n += 2
+			} else if n < len(params) && params[n] == "2" {
+				n += 4
+			}
+			if n > len(params) {
+				n = len(params)
+			}
+			state = append(state[:len(state):len(state)], params[i:n]...)
+			i = n - 1
+		default:
+			state = append(state[:len(state):len(state)], params[i])
+		}
+	}
+	return state
+}
+
+// applyAll returns state changed by all SGR sequences in text
+func (state sgrState) applyAll(text string) sgrState {
+	if !strings.Contains(text, "\033") {
+		return state
+	}
+	for _, seq := range escapeRegexp.FindAllString(text, -1) {
+		state = state.apply(seq)
+	}
+	return state
+}
+
+// String returns SGR sequence which restores state
+func (state sgrState) String() string {
+	if len(state) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(state, ";") + "m"
+}
+
+// withPrefix adds prefix to each line of content. colors of input continued from the previous line are restored after prefix.
+func withPrefix(content string, prefix string) string {
+	var b strings.Builder
+	var state sgrState
+	for _, line := range strings.SplitAfter(content, "\n") {
+		b.WriteString(prefix + state.String() + line)
+		state = state.applyAll(line)
+	}
+	return b.String()
+}
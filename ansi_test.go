@@ -0,0 +1,103 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestVisibleText(t *testing.T) {
+	text := "a\033[31mbc\033[0m\033]0;title\007d"
+	visible, offsets := visibleText(text)
+	if visible != "abcd" {
+		t.Errorf("got %q, want %q", visible, "abcd")
+	}
+	want := []int{0, 6, 7, 22, len(text)}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("got offsets %v, want %v", offsets, want)
+	}
+}
+
+func TestMatchEscaped(t *testing.T) {
+	k := &kolorit{}
+	none := regexp.MustCompile("")
+	tests := []struct {
+		name    string
+		pattern string
+		erase   *regexp.Regexp
+		text    string
+		want    string
+		spans   []span
+	}{
+		{"escape outside of match", "(?P<red>error)", none, "\033[1merror\033[0m!", "\033[1merror\033[0m!", []span{{4, 9, "r"}}},
+		{"escape inside of match", "(?P<red>error)", none, "er\033[1mror", "er\033[1mror", []span{{0, 2, "r"}, {6, 9, "r"}}},
+		{"regexp is not matched with escape", "(?P<red>\\d+)", none, "\033[31mx\033[0m", "\033[31mx\033[0m", []span{}},
+		{"anchors with visible text", "(?P<red>^go$)", none, "\033[32mgo\033[0m", "\033[32mgo\033[0m", []span{{5, 7, "r"}}},
+		{"erase with escapes", "(?P<red>ab)", regexp.MustCompile("x+"), "a\033[1mxx\033[0mb", "a\033[1m\033[0mb", []span{{0, 1, "r"}, {9, 10, "r"}}},
+		{"erase around escape", "(?P<red>z)", regexp.MustCompile("xy"), "ax\033[1myb", "a\033[1mb", []span{}},
+	}
+	for _, tt := range tests {
+		text, spans, _ := k.matchEscaped(regexp.MustCompile(tt.pattern), tt.erase, tt.text)
+		if text != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, text, tt.want)
+		}
+		if !reflect.DeepEqual(spans, tt.spans) {
+			t.Errorf("%s: got spans %v, want %v", tt.name, spans, tt.spans)
+		}
+	}
+}
+
+func TestPlainOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		spans []span
+		want  string
+		moved []span
+	}{
+		{"no escape", "error", []span{{0, 5, "r"}}, "error", []span{{0, 5, "r"}}},
+		{"split span is joined", "er\033[1mror", []span{{0, 2, "r"}, {6, 9, "r"}}, "error", []span{{0, 5, "r"}}},
+		{"adjacent spans are kept", "aa\033[1m", []span{{0, 1, "r"}, {1, 2, "r"}}, "aa", []span{{0, 1, "r"}, {1, 2, "r"}}},
+		{"span after escape", "\033[31mx\033[0m err", []span{{11, 14, "y"}}, "x err", []span{{2, 5, "y"}}},
+	}
+	for _, tt := range tests {
+		text, spans := plainOf(tt.text, tt.spans)
+		if text != tt.want || !reflect.DeepEqual(spans, tt.moved) {
+			t.Errorf("%s: got %q %v, want %q %v", tt.name, text, spans, tt.want, tt.moved)
+		}
+	}
+}
+
+func TestSGRState(t *testing.T) {
+	tests := []struct {
+		name string
+		seqs []string
+		want string
+	}{
+		{"colors are accumulated", []string{"\033[1m", "\033[31m"}, "\033[1;31m"},
+		{"reset", []string{"\033[1;31m", "\033[0m"}, ""},
+		{"empty parameter is reset", []string{"\033[1m", "\033[m", "\033[4m"}, "\033[4m"},
+		{"reset in the middle", []string{"\033[1m", "\033[0;32m"}, "\033[32m"},
+		{"256 colors", []string{"\033[38;5;0;1m"}, "\033[38;5;0;1m"},
+		{"true colors", []string{"\033[48;2;0;0;0m", "\033[4m"}, "\033[48;2;0;0;0;4m"},
+		{"truncated extended color", []string{"\033[38;5m"}, "\033[38;5m"},
+		{"not SGR", []string{"\033[2K", "\033[?25l", "\033]0;t\007"}, ""},
+	}
+	for _, tt := range tests {
+		var state sgrState
+		for _, seq := range tt.seqs {
+			state = state.apply(seq)
+		}
+		if state.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, state.String(), tt.want)
+		}
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	got := withPrefix("\033[31ma\nb\033[0m\nc", "> ")
+	want := "> \033[31ma\n> \033[31mb\033[0m\n> c"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// separator of archive file name and member name, e.g. bundle.tar.gz!var/log/app.log
+const archiveSep = "!"
+
+// archives which can be read with -archives
+var archiveGlobs = []string{"*.tar", "*.tar.gz", "*.tgz", "*.tar.bz2", "*.tbz", "*.tbz2", "*.zip"}
+
+func isReadableArchive(file string) bool {
+	name := strings.ToLower(filepath.Base(file))
+	for _, glob := range archiveGlobs {
+		if matched, _ := filepath.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isTar checks magic of tar archive without consuming it
+func isTar(br *bufio.Reader) bool {
+	header, _ := br.Peek(262)
+	return len(header) == 262 && string(header[257:262]) == "ustar"
+}
+
+// readArchive reads members of tar or zip archive as files.
+// archive is detected by magic bytes and it returns false when file is not an archive.
+func (kolorit *kolorit) readArchive(file string) bool {
+	if file == stdinName {
+		return false
+	}
+	fp, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer fp.Close()
+
+	magic := make([]byte, 4)
+	if n, _ := io.ReadFull(fp, magic); n == 4 && (bytes.Equal(magic, []byte("PK\x03\x04")) || bytes.Equal(magic, []byte("PK\x05\x06"))) {
+		fi, err := fp.Stat()
+		errCheck(err, "error on stat file: "+file)
+		zr, err := zip.NewReader(fp, fi.Size())
+		if err != nil {
+			log.Println(err.Error() + " :error on reading archive: " + file)
+			return true
+		}
+		for _, f := range zr.File {
+			member := memberName(f.Name)
+			if !kolorit.isArchiveMember(member, f.FileInfo()) {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				log.Println(err.Error() + " :error on reading archive: " + file + archiveSep + member)
+				continue
+			}
+			kolorit.readMember(r, file, member)
+			r.Close()
+		}
+		return true
+	}
+
+	if _, err := fp.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	var r io.Reader = fp
+	if !kolorit.options["nz"] {
+		if r, err = decompress(ioutil.NopCloser(fp), file); err != nil {
+			return false
+		}
+	}
+	br := bufio.NewReader(r)
+	if !isTar(br) {
+		return false
+	}
+	tr := tar.NewReader(br)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Println(err.Error() + " :error on reading archive: " + file)
+			break
+		}
+		member := memberName(h.Name)
+		if !h.FileInfo().Mode().IsRegular() || !kolorit.isArchiveMember(member, h.FileInfo()) {
+			continue
+		}
+		kolorit.readMember(tr, file, member)
+	}
+	return true
+}
+
+// memberName cleans name of archive member like './var/log/app.log' to 'var/log/app.log'
+func memberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// isArchiveMember checks member of archive with the same filters as seekDir
+func (kolorit *kolorit) isArchiveMember(name string, fi os.FileInfo) bool {
+	if fi.IsDir() {
+		return false
+	}
+	dirs := strings.Split(name, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if kolorit.isIgnoreDirs(dir) {
+			return false
+		}
+	}
+	base := dirs[len(dirs)-1]
+	// archives in archive are not read
+	if isReadableArchive(base) || kolorit.isIgnoreFile(base) {
+		return false
+	}
+	if kolorit.fileName != "" && !kolorit.checkFileName(name) {
+		return false
+	}
+	return kolorit.matchFileInfo(fi)
+}
+
+func (kolorit *kolorit) readMember(r io.Reader, file string, member string) {
+	name := displayName(file) + archiveSep + member
+	if !kolorit.options["nz"] {
+		dr, err := decompress(ioutil.NopCloser(r), member)
+		if err != nil {
+			log.Println(err.Error() + " :error on reading file: " + name)
+			return
+		}
+		r = dr
+	}
+	kolorit.readInput(r, name)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// name of environment variable which has default options
+const optsEnv = "KOLORIT_OPTS"
+
+// normalizeArgs converts GNU-style arguments into arguments which flag package can parse.
+// options can be given after file names, as "--name" or "-name", with "=value" or the next argument as value,
+// and single character options can be combined(e.g. "-Bi", "-Br ERROR").
+// arguments after "--" are not regarded as options, and they are also returned as dashed.
+func normalizeArgs(fs *flag.FlagSet, args []string) (normalized []string, dashed []string, err error) {
+	options := make([]string, 0, len(args))
+	rest := make([]string, 0)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			dashed = args[i+1:]
+			rest = append(rest, dashed...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			rest = append(rest, arg)
+			continue
+		}
+
+		name := strings.TrimPrefix(arg[1:], "-")
+		value, hasValue := "", false
+		if n := strings.Index(name, "="); n >= 0 {
+			name, value, hasValue = name[:n], name[n+1:], true
+		}
+		if fs.Lookup(name) == nil && !hasValue && !strings.HasPrefix(arg, "--") {
+			// combined single character options
+			expanded := expandShortOptions(fs, name)
+			if len(expanded) == 0 {
+				return nil, nil, errors.New("flag provided but not defined: " + arg)
+			}
+			last := expanded[len(expanded)-1]
+			if !strings.Contains(last, "=") && !isBoolFlag(fs, last[1:]) {
+				if i+1 >= len(args) {
+					return nil, nil, errors.New("flag needs an argument: -" + last[1:])
+				}
+				i++
+				expanded[len(expanded)-1] = last + "=" + args[i]
+			}
+			options = append(options, expanded...)
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return nil, nil, errors.New("flag provided but not defined: " + arg)
+		}
+		if !hasValue && !isBoolFlag(fs, name) {
+			if i+1 >= len(args) {
+				return nil, nil, errors.New("flag needs an argument: " + arg)
+			}
+			i++
+			value, hasValue = args[i], true
+		}
+		if hasValue {
+			options = append(options, "-"+name+"="+value)
+		} else {
+			options = append(options, "-"+name)
+		}
+	}
+	return append(append(options, "--"), rest...), dashed, nil
+}
+
+// expandShortOptions expands "Ber" into "-B", "-e=r". it returns nil when name is not combined options.
+// options other than the last one must be booleans, and the rest of name after non-boolean option is its value.
+func expandShortOptions(fs *flag.FlagSet, name string) []string {
+	expanded := make([]string, 0, len(name))
+	for i, c := range name {
+		opt := string(c)
+		if fs.Lookup(opt) == nil {
+			return nil
+		}
+		if isBoolFlag(fs, opt) {
+			expanded = append(expanded, "-"+opt)
+			continue
+		}
+		if value := name[i+len(opt):]; value != "" {
+			expanded = append(expanded, "-"+opt+"="+value)
+		} else {
+			expanded = append(expanded, "-"+opt)
+		}
+		break
+	}
+	return expanded
+}
+
+func isBoolFlag(fs *flag.FlagSet, name string) bool {
+	b, ok := fs.Lookup(name).Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// parseArgs parses GNU-style arguments with fs, and returns arguments after "--"
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	normalized, dashed, err := normalizeArgs(fs, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	fs.Parse(normalized)
+	return dashed
+}
+
+// parseEnvArgs parses options in KOLORIT_OPTS with another flag set which shares values with fs.
+// they are parsed before command line and are regarded as default values, so they are not visited by fs.Visit,
+// and config files and command line take precedence over them.
+func parseEnvArgs(fs *flag.FlagSet) {
+	env := flag.NewFlagSet(optsEnv, flag.ContinueOnError)
+	fs.VisitAll(func(f *flag.Flag) {
+		env.Var(f.Value, f.Name, f.Usage)
+	})
+	args, err := splitWords(os.Getenv(optsEnv))
+	if err != nil {
+		errMessage(err.Error() + " in " + optsEnv)
+	}
+	normalized, dashed, err := normalizeArgs(env, args)
+	if err != nil {
+		errMessage(err.Error() + " in " + optsEnv)
+	}
+	if dashed != nil || normalized[len(normalized)-1] != "--" {
+		errMessage("only options can be given in " + optsEnv)
+	}
+	env.SetOutput(ioutil.Discard)
+	if err := env.Parse(normalized); err != nil {
+		errMessage(err.Error() + " in " + optsEnv)
+	}
+}
+
+// splitWords splits string into words like shell. words can be quoted with ' or ", and \ escapes the next character.
+func splitWords(s string) ([]string, error) {
+	words := make([]string, 0)
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func testFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("B", false, "")
+	fs.Bool("i", false, "")
+	fs.Bool("grep", false, "")
+	fs.String("r", "", "")
+	fs.String("max-filesize", "", "")
+	return fs
+}
+
+func TestNormalizeArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		normalized []string
+		dashed     []string
+		isError    bool
+	}{
+		{"options after files", []string{"a.log", "-r", "ERROR"}, []string{"-r=ERROR", "--", "a.log"}, nil, false},
+		{"double dash with value", []string{"--max-filesize=10M", "--grep"}, []string{"-max-filesize=10M", "-grep", "--"}, nil, false},
+		{"double dash and next argument", []string{"--r", "x=y"}, []string{"-r=x=y", "--"}, nil, false},
+		{"combined bool flags", []string{"-Bi"}, []string{"-B", "-i", "--"}, nil, false},
+		{"combined with value", []string{"-Br", "ERROR"}, []string{"-B", "-r=ERROR", "--"}, nil, false},
+		{"combined with attached value", []string{"-BrERROR"}, []string{"-B", "-r=ERROR", "--"}, nil, false},
+		{"arguments after --", []string{"-B", "--", "-i", "a.log"}, []string{"-B", "--", "-i", "a.log"}, []string{"-i", "a.log"}, false},
+		{"single dash is a file", []string{"-", "-i"}, []string{"-i", "--", "-"}, nil, false},
+		{"unknown flag", []string{"-x"}, nil, nil, true},
+		{"unknown combined flag", []string{"-Bx"}, nil, nil, true},
+		{"missing value", []string{"-r"}, nil, nil, true},
+		{"missing value of combined flags", []string{"-Br"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		normalized, dashed, err := normalizeArgs(testFlagSet(), tt.args)
+		if (err != nil) != tt.isError {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if tt.isError {
+			continue
+		}
+		if !reflect.DeepEqual(normalized, tt.normalized) {
+			t.Errorf("%s: got %q, want %q", tt.name, normalized, tt.normalized)
+		}
+		if !reflect.DeepEqual(dashed, tt.dashed) {
+			t.Errorf("%s: got dashed %q, want %q", tt.name, dashed, tt.dashed)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		s       string
+		words   []string
+		isError bool
+	}{
+		{"-B --max-filesize 10M", []string{"-B", "--max-filesize", "10M"}, false},
+		{`-r 'a b' -y "c d"`, []string{"-r", "a b", "-y", "c d"}, false},
+		{`-r a\ b ''`, []string{"-r", "a b", ""}, false},
+		{`-r 'a`, nil, true},
+		{`-r a\`, nil, true},
+	}
+	for _, tt := range tests {
+		words, err := splitWords(tt.s)
+		if (err != nil) != tt.isError {
+			t.Errorf("%s: unexpected error: %v", tt.s, err)
+			continue
+		}
+		if !tt.isError && !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("%s: got %q, want %q", tt.s, words, tt.words)
+		}
+	}
+}
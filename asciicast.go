@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// asciicastRenderer writes colored text as asciicast v2 of asciinema.
+// each line is an event at the time when it is read.
+type asciicastRenderer struct {
+	start time.Time
+}
+
+func (a *asciicastRenderer) begin(w io.Writer) {
+	a.start = time.Now()
+	width, height := 80, 24
+	for _, f := range []*os.File{os.Stdin, os.Stderr} {
+		if cols, rows, err := term.GetSize(int(f.Fd())); err == nil && cols > 0 && rows > 0 {
+			width, height = cols, rows
+			break
+		}
+	}
+	header := map[string]interface{}{
+		"version": 2, "width": width, "height": height, "timestamp": a.start.Unix(),
+		"env": map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	}
+	b, err := json.Marshal(header)
+	errCheck(err, "error on writing asciicast")
+	fmt.Fprintln(w, string(b))
+}
+
+func (a *asciicastRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	colored := kolorit.addPrefix(kolorit.renderANSI(text, spans), name, ln)
+	// terminals output "\r\n" for newline
+	event := []interface{}{float64(time.Since(a.start).Microseconds()) / 1e6, "o", strings.Replace(colored, "\n", "\r\n", -1) + "\r\n"}
+	b, err := json.Marshal(event)
+	errCheck(err, "error on writing asciicast")
+	fmt.Fprintln(w, string(b))
+}
+
+func (a *asciicastRenderer) end(w io.Writer) {}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"log"
+	"path/filepath"
+	"regexp"
+)
+
+// autoProfile is a profile which is selected automatically for input
+type autoProfile struct {
+	name     string
+	files    []string
+	commands []string
+	content  *regexp.Regexp
+}
+
+// autoProfiles returns profiles which have match_files, match_command or match_content.
+// these keys are read from the section itself and are not inherited with 'extends'.
+// built-in profiles which are not defined in config files are included only with builtin.
+func (c *config) autoProfiles(builtin bool) ([]*autoProfile, error) {
+	profiles := make([]*autoProfile, 0)
+	patterns, err := c.patterns()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range c.profileNames() {
+		if !builtin && len(c.definedInFiles(name)) == 0 {
+			continue
+		}
+		section := c.section(name)
+		a := &autoProfile{name: name}
+		if a.files, err = stringList(section.values["match_files"]); err != nil {
+			return nil, errors.New("'match_files' of '" + name + "' " + err.Error())
+		}
+		if a.commands, err = stringList(section.values["match_command"]); err != nil {
+			return nil, errors.New("'match_command' of '" + name + "' " + err.Error())
+		}
+		if v, ok := section.values["match_content"]; ok {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("'match_content' of '" + name + "' must be a string")
+			}
+			if s, err = expandPatterns(s, patterns); err != nil {
+				return nil, errors.New(err.Error() + " in 'match_content' of '" + name + "'")
+			}
+			if a.content, err = regexp.Compile("(?m)" + s); err != nil {
+				return nil, errors.New("wrong regexp in 'match_content' of '" + name + "': " + err.Error())
+			}
+		}
+		if len(a.files) > 0 || len(a.commands) > 0 || a.content != nil {
+			profiles = append(profiles, a)
+		}
+	}
+	return profiles, nil
+}
+
+// givesRegexps returns whether regexps of colors or -e are given in command line.
+// profile is not selected automatically for them not to be replaced by profile.
+func (kolorit *kolorit) givesRegexps() bool {
+	for _, k := range colorNames {
+		if kolorit.cliOptions[k] {
+			return true
+		}
+	}
+	return kolorit.cliOptions["e"]
+}
+
+// matchProfile selects profile for input. profiles are checked by file name,
+// command name and then content, and the first matched profile in order of name is selected.
+// content is not checked when br is nil.
+func (kolorit *kolorit) matchProfile(name string, br *bufio.Reader) string {
+	if name != stdinName {
+		for _, a := range kolorit.autoProfiles {
+			for _, glob := range a.files {
+				if matchGlob(glob, name) {
+					return a.name
+				}
+			}
+		}
+	} else if command := kolorit.strOptions["command"]; command != "" {
+		for _, a := range kolorit.autoProfiles {
+			for _, c := range a.commands {
+				if c == filepath.Base(command) {
+					return a.name
+				}
+			}
+		}
+	}
+
+	var head []byte
+	for _, a := range kolorit.autoProfiles {
+		if a.content == nil || br == nil {
+			continue
+		}
+		if head == nil {
+			head = sniff(br)
+		}
+		if a.content.Match(head) {
+			return a.name
+		}
+	}
+	return ""
+}
+
+// matchGlob matches glob with base name of file and also with whole path
+func matchGlob(glob string, file string) bool {
+	if matched, _ := filepath.Match(glob, filepath.Base(file)); matched {
+		return true
+	}
+	matched, _ := filepath.Match(glob, file)
+	return matched
+}
+
+// sniff returns head of content without consuming it.
+// it waits only the first line not to block reading stream.
+func sniff(br *bufio.Reader) []byte {
+	_, err := br.Peek(1)
+	for {
+		head, _ := br.Peek(br.Buffered())
+		if err != nil || bytes.IndexByte(head, '\n') >= 0 || len(head) == br.Size() {
+			return head
+		}
+		_, err = br.Peek(len(head) + 1)
+	}
+}
+
+// forInput returns kolorit with profile selected for input.
+// it returns kolorit itself when -use is given or no profile is matched.
+func (kolorit *kolorit) forInput(name string, br *bufio.Reader) *kolorit {
+	if len(kolorit.autoProfiles) == 0 {
+		return kolorit
+	}
+	selected := kolorit.matchProfile(name, br)
+	if selected == "" {
+		return kolorit
+	}
+	if isDebug {
+		log.Println("Selected Profile: " + selected + " for " + displayName(name))
+	}
+	return kolorit.withSelected(selected)
+}
+
+// withSelected returns kolorit with selected profile. it is built only once for each profile.
+func (kolorit *kolorit) withSelected(selected string) *kolorit {
+	k, ok := kolorit.selected[selected]
+	if !ok {
+		k = kolorit.withProfile(selected)
+		kolorit.selected[selected] = k
+	}
+	return k
+}
+
+// withProfile returns copy of kolorit which options are given in command line and profile
+func (kolorit *kolorit) withProfile(name string) *kolorit {
+	p, err := kolorit.config.effectiveProfile([]string{name})
+	if err != nil {
+		errMessage(err.Error())
+	}
+	return kolorit.withValues(p)
+}
+
+// withValues returns copy of kolorit which options are given in command line and p
+func (kolorit *kolorit) withValues(p *profile) *kolorit {
+	base := kolorit.base.copyOptions()
+	k := *kolorit
+	k.options, k.strOptions, k.intOptions = base.options, base.strOptions, base.intOptions
+	k.regexps, k.bgColors = base.regexps, base.bgColors
+	k.applyProfile(p)
+	k.prepare()
+	return &k
+}
+
+// copyOptions returns copy of kolorit which has its own maps of options
+func (kolorit *kolorit) copyOptions() *kolorit {
+	k := *kolorit
+	k.options = make(map[string]bool)
+	for key, v := range kolorit.options {
+		k.options[key] = v
+	}
+	k.strOptions = make(map[string]string)
+	for key, v := range kolorit.strOptions {
+		k.strOptions[key] = v
+	}
+	k.intOptions = make(map[string]int)
+	for key, v := range kolorit.intOptions {
+		k.intOptions[key] = v
+	}
+	k.regexps = make(map[string]string)
+	for key, v := range kolorit.regexps {
+		k.regexps[key] = v
+	}
+	k.bgColors = make(map[string]string)
+	for key, v := range kolorit.bgColors {
+		k.bgColors[key] = v
+	}
+	return &k
+}
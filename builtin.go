@@ -0,0 +1,177 @@
+package main
+
+import (
+	toml "github.com/pelletier/go-toml"
+)
+
+// path of built-in profiles shown in messages
+const builtinPath = "built-in"
+
+// builtinProfiles are profiles compiled into the binary. they are overridden by sections of the same name in config files.
+const builtinProfiles = `
+[date_time]
+y = '{{date}}'
+b = '{{time}}'
+
+[[date_time.tests]]
+input = "2017-01-01 10:00:00"
+expect = "y:0-10 b:11-19"
+
+[rsync]
+match_command = "rsync"
+g = 'sending incremental file list(.+?)\nsent [\d.]+\w bytes'
+s = true
+B = true
+
+[syslog]
+match_files = ["syslog", "syslog.*", "messages", "messages.*", "*.syslog"]
+y = '^\w{3} [ \d]\d \d{2}:\d{2}:\d{2}'
+b = '[\w./-]+(?:\[\d+\])?:'
+r = '(?i)\b(?:error|err|fail(?:ed|ure)?|crit(?:ical)?|alert|emerg(?:ency)?|panic|fatal|denied|invalid)\b'
+p = '(?i)\bwarn(?:ing)?\b'
+g = '{{ipv4}}'
+
+[[syslog.tests]]
+input = "Jan  2 10:11:12 host sshd[123]: Failed password for root from 10.0.0.1 port 22"
+expect = "y:0-15 b:21-31 r:32-38 g:62-70"
+
+[nginx]
+match_files = ["access.log", "access.log.*", "*access*.log"]
+match_content = '^\S+ \S+ \S+ \[[^\]]+\] "'
+g = '^\S+'
+y = '\[[^\]]+\]'
+c = '"(?:GET|POST|PUT|DELETE|HEAD|OPTIONS|PATCH|CONNECT|TRACE) [^"]*"'
+lg = ' (2\d\d) '
+lc = ' (3\d\d) '
+p = ' (4\d\d) '
+r = ' (5\d\d) '
+dgr = '"[^"]*"'
+
+[[nginx.tests]]
+input = '10.0.0.2 - - [10/Oct/2023:13:55:37 +0000] "POST /api HTTP/1.1" 502 157 "-" "curl/8.0"'
+expect = "g:0-8 y:13-41 c:42-62 r:63-66 dgr:71-74 dgr:75-85"
+
+[[nginx.tests]]
+input = '127.0.0.1 - - [10/Oct/2023:13:55:36 +0000] "GET /index.html HTTP/1.1" 200 2326 "-" "curl/8.0"'
+expect = "g:0-9 y:14-42 c:43-69 lg:70-73 dgr:79-82 dgr:83-93"
+
+[apache]
+extends = ["nginx"]
+match_files = ["access_log", "access_log.*", "*_access_log"]
+
+[go-test]
+g = '^\s*--- PASS: \S+|^PASS$|^ok\s+\S+'
+r = '^\s*--- FAIL: \S+|^FAIL\b.*|^panic:.*'
+y = '^\s*--- SKIP: \S+'
+c = '[\w./-]+\.go:\d+'
+b = '\(\d+(?:\.\d+)?s\)'
+dgr = '^=== (?:RUN|PAUSE|CONT|NAME)\s+\S+'
+
+[[go-test.tests]]
+input = "=== RUN   TestFoo\n    foo_test.go:12: got 1\n--- FAIL: TestFoo (0.01s)\n--- PASS: TestBar (0.00s)\nok  \texample.com/pkg\t0.003s"
+expect = "dgr:0-17 c:22-36 r:44-61 b:62-69 g:70-87 b:88-95 g:96-116"
+
+[go-build]
+c = '^# \S+'
+y = '^[\w./-]+\.go:\d+(?::\d+)?'
+r = ': (.+)$'
+
+[[go-build.tests]]
+input = "# example.com/pkg\n./main.go:10:2: undefined: foo"
+expect = "c:0-17 y:18-32 r:34-48"
+
+[make]
+match_command = ["make", "gmake"]
+r = '^g?make(?:\[\d+\])?: \*\*\*.*|\berror\b:?'
+p = '\bwarning\b:?'
+y = '^g?make(?:\[\d+\])?: (?:Nothing to be done|.* is up to date).*'
+c = '^[\w./-]+:\d+(?::\d+)?'
+dgr = '^g?make(?:\[\d+\])?: (?:Entering|Leaving) directory .*'
+
+[[make.tests]]
+input = "make[1]: Entering directory '/src'\nmain.c:3:5: error: expected ';'\nmake: *** [Makefile:3: all] Error 1"
+expect = "dgr:0-34 c:35-45 r:47-53 r:67-102"
+
+[diff]
+match_command = ["diff", "colordiff"]
+match_files = ["*.diff", "*.patch"]
+match_content = '^diff (?:--git|-u|-r) '
+r = '^(?:-(?:$|[^-]|-$|-[^-]).*|<.*)'
+g = '^(?:\+(?:$|[^+]|\+$|\+[^+]).*|>.*)'
+b = '^(?:diff|index|---|\+\+\+) .*'
+c = '^@@ .* @@'
+
+[[diff.tests]]
+input = "--- a/x.go\n+++ b/x.go\n@@ -1,2 +1,2 @@\n-old\n+new\n same"
+expect = "b:0-10 b:11-21 c:22-37 r:38-42 g:43-47"
+
+[ps]
+match_command = "ps"
+b = '^\s*(?:USER|UID|PID|PPID)\b.*'
+r = '\broot\b'
+g = '\b\d+:\d{2}(?::\d{2})?\b'
+y = '\b\d+(?:\.\d+)?\b'
+
+[[ps.tests]]
+input = "  PID TTY          TIME CMD\n    1 ?        00:00:03 systemd"
+expect = "b:0-27 y:32-33 g:43-51"
+
+[df]
+match_command = "df"
+b = '^Filesystem\b.*'
+r = '\b(?:9\d|100)%'
+y = '\b[78]\d%'
+g = '\b[1-6]?\d%'
+c = '\s(/\S*)$'
+
+[[df.tests]]
+input = "Filesystem     1K-blocks    Used Available Use% Mounted on\n/dev/sda1       98298788 9013528  84235960  10% /\n/dev/sdb1       98298788 9013528  84235960  95% /data"
+expect = "b:0-58 g:103-106 c:107-108 r:153-156 c:157-162"
+
+[ping]
+match_command = ["ping", "ping6"]
+b = '^PING .*'
+r = '(?i)request time(?:out|d out)|unreachable|unknown host|\b(?:[1-9]\d*(?:\.\d+)?|0\.\d*[1-9]\d*)% packet loss'
+g = '{{ipv4}}|\b0(?:\.0+)?% packet loss'
+y = 'time[=<][\d.]+ ?ms'
+c = 'icmp_seq=\d+'
+p = '^(?:rtt|round-trip) .*'
+
+[[ping.tests]]
+input = "64 bytes from 93.184.216.34: icmp_seq=1 ttl=56 time=11.2 ms\n3 packets transmitted, 3 received, 0% packet loss, time 2003ms\nrtt min/avg/max/mdev = 11.1/11.2/11.3/0.1 ms"
+expect = "g:14-27 c:29-39 y:47-59 g:95-109 p:123-167"
+
+[dig]
+match_command = "dig"
+b = '^;; [A-Z ]+SECTION:'
+r = 'status: (?:NXDOMAIN|SERVFAIL|REFUSED|FORMERR|NOTIMP|YXDOMAIN|NXRRSET)'
+g = 'status: NOERROR|{{ipv4}}'
+c = '^([\w.-]+\.)\s'
+y = '\sIN\s+([A-Z]+)\b'
+dgr = '^;(?:[^;].*)?$'
+
+[[dig.tests]]
+input = ";; ->>HEADER<<- opcode: QUERY, status: NOERROR, id: 1\n;; ANSWER SECTION:\nexample.com.\t\t300\tIN\tA\t93.184.216.34"
+expect = "g:31-46 b:54-72 c:73-85 y:94-95 g:96-109"
+
+[json-log]
+match_files = ["*.json.log", "*.jsonl", "*.ndjson"]
+match_content = '^\s*\{\s*"'
+r = '("(?i:error|err|fatal|panic|crit(?:ical)?)")\s*[,}]'
+g = '("(?i:info|notice)")\s*[,}]'
+y = '("(?i:warn(?:ing)?)")\s*[,}]'
+dgr = '("(?i:debug|trace)")\s*[,}]'
+b = '"{{datetime}}"'
+c = '("[\w.@-]+")\s*:'
+
+[[json-log.tests]]
+input = '{"time":"2024-01-02T10:11:12.345Z","level":"error","msg":"failed"}'
+expect = "c:1-7 b:8-34 c:35-42 r:43-50 c:51-56"
+`
+
+// builtinConfigFile returns built-in profiles as a config file which has the lowest precedence
+func builtinConfigFile() *configFile {
+	tree, err := toml.Load(builtinProfiles)
+	errCheck(err, "error on parsing built-in profiles")
+	return &configFile{path: builtinPath, tree: tree, builtin: true}
+}
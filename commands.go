@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	toml "github.com/pelletier/go-toml"
+)
+
+// commands given as the first argument instead of options
+var commands = map[string]func(args []string){
+	"profile":  profileCommand,
+	"profiles": profileCommand,
+	"config":   configCommand,
+	"import":   importCommand,
+}
+
+func init() {
+	// added here because shell-init refers to commands
+	commands["shell-init"] = shellInitCommand
+}
+
+// commandOf returns subcommand given as the first of args. a file which has the same name as a subcommand
+// is regarded as input(e.g. "kolorit config" colors ./config when it exists).
+func commandOf(args []string) (func(args []string), bool) {
+	if len(args) == 0 || isFile(args[0], false) {
+		return nil, false
+	}
+	command, ok := commands[args[0]]
+	return command, ok
+}
+
+func commandUsage(usage string) {
+	fmt.Println("Usage:\n\n  " + usage)
+	os.Exit(1)
+}
+
+// newCommandFlags returns flags for command with -conf option
+func newCommandFlags(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	conf := flags.String("conf", "", "path of config file. only the file is read when it is given")
+	return flags, conf
+}
+
+// profileCommand lists profiles or shows effective options of profiles merged with [default] and 'extends'
+func profileCommand(args []string) {
+	usage := "kolorit profile show [-conf FILE] NAME[,NAME...]\n  kolorit profiles list [-conf FILE]"
+	if len(args) == 0 || (args[0] != "show" && args[0] != "list") {
+		commandUsage(usage)
+	}
+	flags, conf := newCommandFlags("profile " + args[0])
+	parseArgs(flags, args[1:])
+	if (args[0] == "show" && flags.NArg() != 1) || (args[0] == "list" && flags.NArg() != 0) {
+		commandUsage(usage)
+	}
+
+	config, err := readConfig(*conf)
+	if err != nil {
+		errMessage(err.Error())
+	}
+	if args[0] == "list" {
+		listProfiles(config)
+		return
+	}
+	uses := splitNames(flags.Arg(0))
+	p, err := config.effectiveProfile(uses)
+	if err != nil {
+		errMessage(err.Error())
+	}
+	comments := make(map[string]string)
+	for k := range p.values {
+		comments[k] = "from " + p.origin(k)
+	}
+	writeTOML(os.Stdout, tomlKey(strings.Join(uses, ",")), p.values, comments)
+}
+
+// listProfiles prints names of profiles and where they are defined
+func listProfiles(config *config) {
+	names := config.profileNames()
+	width := 0
+	for _, name := range names {
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	for _, name := range names {
+		paths := config.definedInFiles(name)
+		source := strings.Join(paths, ", ")
+		if len(paths) == 0 {
+			source = builtinPath
+		} else if _, ok := config.files[0].tree.Get(name).(*toml.TomlTree); ok {
+			source += " (overrides " + builtinPath + ")"
+		}
+		fmt.Printf("%-*s  %s\n", width, name, source)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommandOf(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "import"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want bool
+	}{
+		{"subcommand", []string{"profile", "list"}, true},
+		{"file which has name of subcommand", []string{"config"}, false},
+		{"file given with path", []string{"./config"}, false},
+		{"directory which has name of subcommand", []string{"import", "grep"}, true},
+		{"not subcommand", []string{"-r", "a"}, false},
+		{"no argument", []string{}, false},
+	}
+	for _, tt := range tests {
+		if _, got := commandOf(tt.args); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml"
+)
+
+// options which cannot be written in profiles
+var profileIgnoredKeys = map[string]bool{
+	"conf": true, "use": true, "help": true, "h": true,
+	"save-profile": true, "show-config-sources": true, "type-list": true, "pattern-list": true, "run": true,
+}
+
+// options which cannot be written in .kolorit.toml of project, because the file may come with untrusted repository
+var untrustedKeys = map[string]bool{"nsanitize": true, "allow-sgr": true, "pty": true, "run": true}
+
+// keys in .kolorit.toml of project which are already warned
+var warnedKeys = make(map[string]bool)
+
+// sections which are not profiles
+var reservedSections = map[string]bool{"default": true, "types": true, "patterns": true}
+
+var bareKeyRegexp = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+
+// profile is a set of options defined in sections of config files
+type profile struct {
+	values map[string]interface{}
+	// name of section which each value comes from
+	from map[string]string
+	// path of config file which each value comes from
+	sources map[string]string
+}
+
+func newProfile() *profile {
+	return &profile{values: make(map[string]interface{}), from: make(map[string]string), sources: make(map[string]string)}
+}
+
+// merge overrides values of p with values of other
+func (p *profile) merge(other *profile) {
+	for k, v := range other.values {
+		p.values[k] = v
+		p.from[k] = other.from[k]
+		p.sources[k] = other.sources[k]
+	}
+}
+
+// origin returns section and file which value of key comes from
+func (p *profile) origin(k string) string {
+	return "[" + p.from[k] + "] in " + p.sources[k]
+}
+
+func (p *profile) keys() []string {
+	keys := make([]string, 0, len(p.values))
+	for k := range p.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type configFile struct {
+	path    string
+	tree    *toml.TomlTree
+	builtin bool
+	// .kolorit.toml of project
+	project bool
+}
+
+// config is merged config files. later files take precedence over earlier ones.
+type config struct {
+	files []*configFile
+	// paths of config files searched
+	searched []string
+}
+
+// configPaths returns paths of config files to be searched in order of precedence from low to high,
+// and path of .kolorit.toml of project in them
+func configPaths() ([]string, string) {
+	paths := []string{filepath.Join(string(os.PathSeparator)+"etc", "kolorit.toml")}
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" && homeDir != "" {
+		xdg = filepath.Join(homeDir, ".config")
+	}
+	if xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "kolorit", "config.toml"))
+	}
+	if homeDir != "" {
+		paths = append(paths, homeDir+".kolorit.toml")
+	}
+	envPaths := filepath.SplitList(os.Getenv("KOLORIT_CONFIG"))
+	project := projectConfigPath(append(paths[:len(paths):len(paths)], envPaths...))
+	if project != "" {
+		paths = append(paths, project)
+	}
+	paths = append(paths, envPaths...)
+
+	uniq := make([]string, 0, len(paths))
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil && !seen[abs] {
+			seen[abs] = true
+			uniq = append(uniq, p)
+		}
+	}
+	return uniq, project
+}
+
+// projectConfigPath searches .kolorit.toml from working directory to its parents.
+// config files of user(e.g. ~/.kolorit.toml found in sub directory of home) are not regarded as project's one.
+func projectConfigPath(userPaths []string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	users := make([]os.FileInfo, 0, len(userPaths))
+	for _, p := range userPaths {
+		if fi, err := os.Stat(p); err == nil {
+			users = append(users, fi)
+		}
+	}
+	isUsers := func(fi os.FileInfo) bool {
+		for _, u := range users {
+			if os.SameFile(fi, u) {
+				return true
+			}
+		}
+		return false
+	}
+	for {
+		p := filepath.Join(dir, ".kolorit.toml")
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() && !isUsers(fi) {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readConfig reads built-in profiles and config files. when path is given, only the file is read.
+// otherwise files returned by configPaths are read if they exist.
+func readConfig(path string) (*config, error) {
+	c := &config{files: []*configFile{builtinConfigFile()}}
+	paths, project := []string{path}, ""
+	if path == "" {
+		paths, project = configPaths()
+	}
+	for _, p := range paths {
+		c.searched = append(c.searched, p)
+		if _, err := os.Stat(p); err != nil {
+			if path != "" {
+				return nil, errors.New("cannot find/read config file: " + p)
+			}
+			continue
+		}
+		tree, err := toml.LoadFile(p)
+		if err != nil {
+			return nil, errors.New("cannot parse config file: " + p + "\nmessage: " + err.Error())
+		}
+		c.files = append(c.files, &configFile{path: p, tree: tree, project: project != "" && p == project})
+	}
+	return c, nil
+}
+
+// describe returns paths of config files for messages
+func (c *config) describe() string {
+	paths := make([]string, 0, len(c.files))
+	for _, f := range c.files {
+		if !f.builtin {
+			paths = append(paths, f.path)
+		}
+	}
+	if len(paths) == 0 {
+		return "config files(no config file is found)"
+	}
+	return strings.Join(paths, ", ")
+}
+
+// definedInFiles returns paths of config files which define section. built-in profiles are not included.
+func (c *config) definedInFiles(name string) []string {
+	paths := make([]string, 0)
+	for _, f := range c.files {
+		if _, ok := f.tree.Get(name).(*toml.TomlTree); ok && !f.builtin {
+			paths = append(paths, f.path)
+		}
+	}
+	return paths
+}
+
+// section returns options written in section of all files without resolving 'extends'.
+// built-in profile is replaced entirely when config files define the same section.
+// it returns nil when section is not defined.
+func (c *config) section(name string) *profile {
+	var p *profile
+	overridden := len(c.definedInFiles(name)) > 0
+	for _, f := range c.files {
+		if f.builtin && overridden {
+			continue
+		}
+		tree, ok := f.tree.Get(name).(*toml.TomlTree)
+		if !ok {
+			continue
+		}
+		if p == nil {
+			p = newProfile()
+		}
+		for _, k := range tree.Keys() {
+			v := fromTOML(tree.Get(k))
+			if f.project {
+				if v = f.trusted(name, k, v); v == nil {
+					continue
+				}
+			}
+			p.values[k] = v
+			p.from[k] = name
+			p.sources[k] = f.path
+		}
+	}
+	return p
+}
+
+// trusted returns value in .kolorit.toml of project without untrustedKeys. it returns nil when key is one of them.
+// values in 'stderr' table are also checked.
+func (f *configFile) trusted(name string, k string, v interface{}) interface{} {
+	if untrustedKeys[k] {
+		if where := f.path + ": " + name + "." + k; !warnedKeys[where] {
+			warnedKeys[where] = true
+			log.Println("'" + k + "' is ignored in " + f.path + ". it can be written only in config files of user(see 'Config file locations' in README)")
+		}
+		return nil
+	}
+	if table, ok := v.(map[string]interface{}); ok && k == "stderr" {
+		for key, value := range table {
+			if f.trusted(name+".stderr", key, value) == nil {
+				delete(table, key)
+			}
+		}
+	}
+	return v
+}
+
+// profileNames returns names of sections which are profiles
+func (c *config) profileNames() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, f := range c.files {
+		for _, k := range f.tree.Keys() {
+			if _, ok := f.tree.Get(k).(*toml.TomlTree); ok && !reservedSections[k] && !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profile returns options of section merged with sections given in its 'extends'.
+// options written in the section take precedence over extended ones,
+// and later sections in 'extends' take precedence over earlier ones.
+func (c *config) profile(name string, chain []string) (*profile, error) {
+	for _, n := range chain {
+		if n == name {
+			return nil, errors.New("circular extends: " + strings.Join(append(chain, name), " -> "))
+		}
+	}
+	own := c.section(name)
+	if own == nil {
+		return nil, errors.New("'" + name + "' is not defined in " + c.describe())
+	}
+	extends, err := stringList(own.values["extends"])
+	if err != nil {
+		return nil, errors.New("'extends' of '" + name + "' " + err.Error())
+	}
+	delete(own.values, "extends")
+	delete(own.from, "extends")
+
+	merged := newProfile()
+	for _, ext := range extends {
+		p, err := c.profile(ext, append(chain[:len(chain):len(chain)], name))
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(p)
+	}
+	merged.merge(own)
+	return merged, nil
+}
+
+// effectiveProfile returns options of [default] merged with profiles given with -use.
+// later profiles take precedence over earlier ones.
+func (c *config) effectiveProfile(uses []string) (*profile, error) {
+	merged := newProfile()
+	if c.section("default") != nil {
+		p, err := c.profile("default", nil)
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(p)
+	}
+	for _, use := range uses {
+		if use == "default" {
+			return nil, errors.New("cannot pass 'default' as 'use' argument")
+		}
+		p, err := c.profile(use, nil)
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(p)
+	}
+	return merged, nil
+}
+
+func (kolorit *kolorit) parseConfig(configFile string, use string) {
+	uses := splitNames(use)
+
+	config, err := readConfig(configFile)
+	if err != nil {
+		if len(uses) > 0 || configFile != "" {
+			errMessage(err.Error())
+		}
+		log.Println(err.Error())
+		return
+	}
+	kolorit.config = config
+	for _, f := range config.files {
+		kolorit.parseFileTypes(f.tree, f.path)
+	}
+	if kolorit.patterns, err = config.patterns(); err != nil {
+		errMessage(err.Error())
+	}
+
+	p, err := config.effectiveProfile(uses)
+	if err != nil {
+		errMessage(err.Error())
+	}
+	if kolorit.options["show-config-sources"] {
+		config.printSources(p, uses)
+		os.Exit(0)
+	}
+	kolorit.applyProfile(p)
+
+	if len(uses) == 0 && kolorit.options["auto"] && !kolorit.givesRegexps() {
+		kolorit.autoProfiles, err = config.autoProfiles(kolorit.options["auto-builtin"])
+		if err != nil {
+			errMessage(err.Error())
+		}
+	}
+}
+
+// printSources prints config files and which file sets each value of effective profile
+func (c *config) printSources(p *profile, uses []string) {
+	fmt.Println("# config files (later ones take precedence)")
+	fmt.Println("#   " + builtinPath + " profiles")
+	for _, path := range c.searched {
+		found := false
+		for _, f := range c.files {
+			found = found || f.path == path
+		}
+		if found {
+			fmt.Println("#   " + path)
+		} else {
+			fmt.Println("#   " + path + " (not found)")
+		}
+	}
+	fmt.Println()
+
+	name := "default"
+	if len(uses) > 0 {
+		name = strings.Join(uses, ",")
+	}
+	comments := make(map[string]string)
+	for k := range p.values {
+		comments[k] = p.origin(k)
+	}
+	writeTOML(os.Stdout, tomlKey(name), p.values, comments)
+
+	types := make(map[string]interface{})
+	comments = make(map[string]string)
+	for _, f := range c.files {
+		tree, ok := f.tree.Get("types").(*toml.TomlTree)
+		if !ok {
+			continue
+		}
+		for _, k := range tree.Keys() {
+			types[k] = tree.Get(k)
+			comments[k] = "in " + f.path
+		}
+	}
+	if len(types) > 0 {
+		fmt.Println()
+		writeTOML(os.Stdout, "types", types, comments)
+	}
+}
+
+// applyProfile sets options from profile. options given in command line take precedence.
+func (kolorit *kolorit) applyProfile(p *profile) {
+	for _, k := range p.keys() {
+		v := p.values[k]
+		where := "'" + k + "' in [" + p.from[k] + "]"
+		if _, ok := colorMap[k]; ok {
+			s, ok := v.(string)
+			if !ok {
+				errMessage(where + " must be a string")
+			}
+			if !kolorit.cliOptions[k] {
+				kolorit.regexps[k] = s
+			}
+			continue
+		}
+		if _, ok := kolorit.bgColors[k]; ok {
+			s, ok := v.(string)
+			if !ok {
+				errMessage(where + " must be a string")
+			}
+			if !kolorit.cliOptions[k] {
+				kolorit.bgColors[k] = s
+			}
+			continue
+		}
+		if k == "rules" {
+			rules, err := parseRules(v)
+			if err != nil {
+				errMessage(where + " " + err.Error())
+			}
+			kolorit.rules = rules
+			continue
+		}
+		def, ok := findOptDef(k)
+		if !ok || profileIgnoredKeys[k] || kolorit.cliOptions[k] {
+			continue
+		}
+		switch {
+		case def.isBool:
+			b, ok := v.(bool)
+			if !ok {
+				errMessage(where + " must be a boolean")
+			}
+			kolorit.options[k] = b
+		case def.isString:
+			s, ok := v.(string)
+			if !ok {
+				errMessage(where + " must be a string")
+			}
+			kolorit.strOptions[k] = s
+		case def.isInt:
+			n, ok := v.(int64)
+			if !ok {
+				errMessage(where + " must be an integer")
+			}
+			kolorit.intOptions[k] = int(n)
+		}
+	}
+	nArry := []string{"grep", "I", "B", "U", "sanitize"}
+	for _, k := range nArry {
+		if kolorit.options["n"+k] {
+			kolorit.options[k] = false
+		}
+	}
+}
+
+func findOptDef(k string) (optDef, bool) {
+	for _, v := range opt {
+		if v.k == k {
+			return v, true
+		}
+	}
+	return optDef{}, false
+}
+
+// splitNames splits comma separated names
+func splitNames(s string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// stringList converts a string or an array of strings to slice
+func stringList(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, s := range v {
+			str, ok := s.(string)
+			if !ok {
+				return nil, errors.New("must be an array of strings")
+			}
+			list = append(list, str)
+		}
+		return list, nil
+	}
+	return nil, errors.New("must be a string or an array of strings")
+}
+
+// fromTOML converts tables in value to maps
+func fromTOML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *toml.TomlTree:
+		m := make(map[string]interface{})
+		for _, k := range v.Keys() {
+			m[k] = fromTOML(v.Get(k))
+		}
+		return m
+	case []*toml.TomlTree:
+		tables := make([]map[string]interface{}, 0, len(v))
+		for _, t := range v {
+			tables = append(tables, fromTOML(t).(map[string]interface{}))
+		}
+		return tables
+	}
+	return v
+}
+
+// writeTOML writes values as a section of TOML. comments are written after values of the same keys.
+func writeTOML(w io.Writer, section string, values map[string]interface{}, comments map[string]string) {
+	fmt.Fprintf(w, "[%s]\n", section)
+	writeTOMLValues(w, section, values, comments)
+}
+
+func writeTOMLValues(w io.Writer, section string, values map[string]interface{}, comments map[string]string) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch values[k].(type) {
+		case map[string]interface{}, []map[string]interface{}:
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s", tomlKey(k), tomlValue(values[k]))
+		if c := comments[k]; c != "" {
+			fmt.Fprint(w, " # "+c)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, k := range keys {
+		switch v := values[k].(type) {
+		case map[string]interface{}:
+			fmt.Fprintf(w, "\n[%s.%s]\n", section, tomlKey(k))
+			writeTOMLValues(w, section+"."+tomlKey(k), v, nil)
+		case []map[string]interface{}:
+			for _, t := range v {
+				fmt.Fprintf(w, "\n[[%s.%s]]\n", section, tomlKey(k))
+				writeTOMLValues(w, section+"."+tomlKey(k), t, nil)
+			}
+		}
+	}
+}
+
+func tomlKey(k string) string {
+	if bareKeyRegexp.MatchString(k) {
+		return k
+	}
+	return tomlBasicString(k)
+}
+
+func tomlValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, "'") && isPrintable(v) {
+			return "'" + v + "'"
+		}
+		return tomlBasicString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEnN") {
+			s += ".0"
+		}
+		return s
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, e := range v {
+			list = append(list, tomlValue(e))
+		}
+		return "[" + strings.Join(list, ", ") + "]"
+	}
+	return tomlBasicString(fmt.Sprint(v))
+}
+
+func tomlBasicString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString("\\n")
+		case r == '\t':
+			b.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\u%04x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isPrintable returns false when s has control characters
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
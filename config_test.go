@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectConfigPath(t *testing.T) {
+	home := t.TempDir()
+	for _, dir := range []string{"sub", filepath.Join("repo", "src")} {
+		if err := os.MkdirAll(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{".kolorit.toml", filepath.Join("repo", ".kolorit.toml")} {
+		if err := os.WriteFile(filepath.Join(home, p), []byte("[default]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := homeDir
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		homeDir = saved
+		os.Chdir(wd)
+	}()
+	homeDir = home + string(os.PathSeparator)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("KOLORIT_CONFIG", "")
+
+	tests := []struct {
+		name    string
+		dir     string
+		project string
+	}{
+		{"config of user in home", "sub", ""},
+		{"config of user in working directory", ".", ""},
+		{"config of project", filepath.Join("repo", "src"), filepath.Join(home, "repo", ".kolorit.toml")},
+	}
+	for _, tt := range tests {
+		if err := os.Chdir(filepath.Join(home, tt.dir)); err != nil {
+			t.Fatal(err)
+		}
+		paths, project := configPaths()
+		if project != tt.project {
+			t.Errorf("%s: project is %q, want %q", tt.name, project, tt.project)
+		}
+		if paths[len(paths)-1] != filepath.Join(home, ".kolorit.toml") && tt.project == "" {
+			t.Errorf("%s: ~/.kolorit.toml is not searched as config of user: %q", tt.name, paths)
+		}
+	}
+}
+
+func TestApplyProfileOverEnvOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   string
+		args  []string
+		r     string
+		br    string
+		depth int
+	}{
+		{"profile overrides env", "-r zzz -br blue -max-depth 3", []string{"-use", "syslog"}, "ERROR", "red", 5},
+		{"command line overrides profile", "-r zzz", []string{"-r", "yyy", "-br=green", "-max-depth", "2", "-use", "syslog"}, "yyy", "green", 2},
+		{"env is used without profile", "-r zzz -br blue", []string{"-use", "other"}, "zzz", "blue", 0},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("kolorit", flag.ContinueOnError)
+		r, br := fs.String("r", "", ""), fs.String("br", "", "")
+		n := fs.Int("max-depth", 0, "")
+		fs.String("use", "", "")
+		t.Setenv(optsEnv, tt.env)
+		parseEnvArgs(fs)
+		parseArgs(fs, tt.args)
+
+		k := newKolorit()
+		k.regexps["r"], k.bgColors["br"], k.intOptions["max-depth"] = *r, *br, *n
+		fs.Visit(func(f *flag.Flag) {
+			k.cliOptions[f.Name] = true
+		})
+		p := newProfile()
+		if fs.Lookup("use").Value.String() == "syslog" {
+			p.values["r"], p.values["br"], p.values["max-depth"] = "ERROR", "red", int64(5)
+		}
+		k.applyProfile(p)
+		if k.regexps["r"] != tt.r || k.bgColors["br"] != tt.br || k.intOptions["max-depth"] != tt.depth {
+			t.Errorf("%s: got r=%q br=%q max-depth=%d, want r=%q br=%q max-depth=%d", tt.name,
+				k.regexps["r"], k.bgColors["br"], k.intOptions["max-depth"], tt.r, tt.br, tt.depth)
+		}
+	}
+}
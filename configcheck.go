@@ -0,0 +1,382 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"sort"
+
+	"github.com/ktat/go-ansistrings"
+	toml "github.com/pelletier/go-toml"
+)
+
+// keys of profiles other than options and their checkers
+var profileKeys = map[string]func(v interface{}) error{
+	"extends":       checkStrings,
+	"match_files":   checkGlobs,
+	"match_command": checkStrings,
+	"match_content": checkRegexp,
+	"tests":         checkTests,
+	"stderr":        checkTable,
+	"rules":         checkRuleTable,
+}
+
+// problem is an error or a warning found in config file
+type problem struct {
+	path    string
+	line    int
+	warning bool
+	message string
+}
+
+func (p problem) String() string {
+	kind := "error"
+	if p.warning {
+		kind = "warning"
+	}
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.path, p.line, kind, p.message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.path, kind, p.message)
+}
+
+type configChecker struct {
+	config   *config
+	patterns map[string]string
+	problems []problem
+	seen     map[string]bool
+}
+
+// checkConfig checks types of values, regexps and keys of all config files
+func checkConfig(c *config) []problem {
+	ch := &configChecker{config: c, seen: make(map[string]bool)}
+	var err error
+	if ch.patterns, err = c.patterns(); err != nil {
+		ch.patterns = builtinPatterns
+	}
+	for _, f := range c.files {
+		for _, name := range f.tree.Keys() {
+			tree, ok := f.tree.Get(name).(*toml.TomlTree)
+			if !ok {
+				ch.add(f.path, f.tree.GetPosition(name).Line, false, "'"+name+"' must be a section")
+				continue
+			}
+			if name == "types" {
+				ch.checkTypes(f, tree)
+				continue
+			}
+			if name == "patterns" {
+				ch.checkPatterns(f, tree)
+				continue
+			}
+			for _, k := range tree.Keys() {
+				v, err := ch.expanded(k, fromTOML(tree.Get(k)))
+				if err == nil {
+					err = checkProfileValue(k, v)
+				}
+				if err != nil {
+					ch.add(f.path, f.tree.GetPosition(name+"."+k).Line, false, "'"+k+"' in ["+name+"] "+err.Error())
+				} else if k == "stderr" {
+					ch.checkStderr(f, name, v.(map[string]interface{}))
+				}
+			}
+		}
+	}
+
+	names := c.profileNames()
+	if c.section("default") != nil {
+		names = append([]string{"default"}, names...)
+	}
+	for _, name := range names {
+		p, err := c.profile(name, nil)
+		if err != nil {
+			ch.addAt(name, "extends", false, err.Error())
+			continue
+		}
+		if name != "default" {
+			if p, err = c.effectiveProfile([]string{name}); err != nil {
+				continue
+			}
+		}
+		ch.checkRules(p)
+	}
+	return ch.problems
+}
+
+func (ch *configChecker) add(path string, line int, warning bool, message string) {
+	p := problem{path: path, line: line, warning: warning, message: message}
+	if !ch.seen[p.String()] {
+		ch.seen[p.String()] = true
+		ch.problems = append(ch.problems, p)
+	}
+}
+
+// addAt adds problem at key of section in the last file which defines it
+func (ch *configChecker) addAt(section string, key string, warning bool, message string) {
+	for i := len(ch.config.files) - 1; i >= 0; i-- {
+		f := ch.config.files[i]
+		if f.tree.Get(section+"."+key) != nil {
+			ch.add(f.path, f.tree.GetPosition(section+"."+key).Line, warning, message)
+			return
+		}
+	}
+}
+
+func (ch *configChecker) checkTypes(f *configFile, tree *toml.TomlTree) {
+	for _, name := range tree.Keys() {
+		if err := checkGlobs(tree.Get(name)); err != nil {
+			ch.add(f.path, f.tree.GetPosition("types."+name).Line, false, "type '"+name+"' "+err.Error())
+		}
+	}
+}
+
+func (ch *configChecker) checkPatterns(f *configFile, tree *toml.TomlTree) {
+	for _, name := range tree.Keys() {
+		s, ok := tree.Get(name).(string)
+		var err error
+		if !ok {
+			err = errors.New("must be a string")
+		} else if s, err = expandPattern(s, ch.patterns, []string{name}); err == nil {
+			err = checkRegexp(s)
+		}
+		if err != nil {
+			ch.add(f.path, f.tree.GetPosition("patterns."+name).Line, false, "'"+name+"' in [patterns] "+err.Error())
+		}
+	}
+}
+
+// checkStderr checks 'stderr' table of profile. it has only options and colors.
+func (ch *configChecker) checkStderr(f *configFile, name string, table map[string]interface{}) {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := ch.expanded(k, table[k])
+		if _, ok := profileKeys[k]; ok {
+			err = errors.New("cannot be written in 'stderr'")
+		} else if err == nil {
+			err = checkProfileValue(k, v)
+		}
+		if err != nil {
+			ch.add(f.path, f.tree.GetPosition(name+".stderr."+k).Line, false, "'"+k+"' in ["+name+".stderr] "+err.Error())
+		}
+	}
+}
+
+// expanded returns value which patterns are expanded when key is for regexp
+func (ch *configChecker) expanded(k string, v interface{}) (interface{}, error) {
+	s, ok := v.(string)
+	if _, isColor := colorMap[k]; !ok || !(isColor || k == "e" || k == "match_content") {
+		return v, nil
+	}
+	return expandPatterns(s, ch.patterns)
+}
+
+// checkProfileValue checks key and type of value in profile
+func checkProfileValue(k string, v interface{}) error {
+	if _, ok := colorMap[k]; ok {
+		return checkRegexp(v)
+	}
+	if len(k) > 1 && k[0] == 'b' && colorMap[k[1:]] != "" {
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if _, err := ansistrings.ColorNumFromName(s); err != nil {
+			return errors.New("has unknown color name: " + s)
+		}
+		return nil
+	}
+	if check, ok := profileKeys[k]; ok {
+		return check(v)
+	}
+	def, ok := findOptDef(k)
+	if !ok {
+		return errors.New("is unknown key")
+	}
+	if profileIgnoredKeys[k] {
+		return errors.New("cannot be written in profiles")
+	}
+	switch {
+	case def.isBool:
+		if _, ok := v.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	case def.isInt:
+		if _, ok := v.(int64); !ok {
+			return errors.New("must be an integer")
+		}
+	case k == "e":
+		return checkRegexp(v)
+	case def.isString:
+		if _, ok := v.(string); !ok {
+			return errors.New("must be a string")
+		}
+	}
+	return nil
+}
+
+func checkStrings(v interface{}) error {
+	_, err := stringList(v)
+	return err
+}
+
+func checkTable(v interface{}) error {
+	if _, ok := v.(map[string]interface{}); !ok {
+		return errors.New("must be a table")
+	}
+	return nil
+}
+
+func checkGlobs(v interface{}) error {
+	globs, err := stringList(v)
+	if err != nil {
+		return err
+	}
+	for _, glob := range globs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return errors.New("has wrong glob: " + glob)
+		}
+	}
+	return nil
+}
+
+func checkRegexp(v interface{}) error {
+	s, ok := v.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+	if _, err := regexp.Compile(s); err != nil {
+		return errors.New("has wrong regexp: " + err.Error())
+	}
+	return nil
+}
+
+// checkRules warns about color regexps which never color text in profile.
+// regexps are joined in order of colorNames, so the earlier one wins when both match at the same position.
+func (ch *configChecker) checkRules(p *profile) {
+	flags := "(?m)"
+	if i, _ := p.values["i"].(bool); i {
+		flags = "(?mi)"
+	}
+	colors := make([]string, 0)
+	expanded := make(map[string]string)
+	for _, k := range colorNames {
+		if v, err := ch.expanded(k, p.values[k]); err == nil {
+			if s, ok := v.(string); ok && s != "" {
+				if _, err := regexp.Compile(s); err == nil {
+					colors = append(colors, k)
+					expanded[k] = s
+				}
+			}
+		}
+	}
+	for j, k := range colors {
+		s := expanded[k]
+		re := regexp.MustCompile(flags + s)
+		if neverMatches(s) {
+			ch.addAt(p.from[k], k, true, "'"+k+"' in ["+p.from[k]+"] can never match")
+			continue
+		}
+		if re.MatchString("") {
+			ch.addAt(p.from[k], k, true, "'"+k+"' in ["+p.from[k]+"] matches empty string")
+		}
+		for _, prev := range colors[:j] {
+			ps := expanded[prev]
+			if ps == s || (regexp.QuoteMeta(s) == s && isWholeMatch(regexp.MustCompile(flags+ps), s)) {
+				ch.addAt(p.from[k], k, true, "'"+k+"' in ["+p.from[k]+"] is shadowed by '"+prev+"' in ["+p.from[prev]+"]")
+				break
+			}
+		}
+	}
+}
+
+func isWholeMatch(re *regexp.Regexp, s string) bool {
+	loc := re.FindStringIndex(s)
+	return loc != nil && loc[0] == 0 && loc[1] == len(s)
+}
+
+// neverMatches returns true when regexp cannot match any string, e.g. '[^\x00-\x{10FFFF}]'
+func neverMatches(s string) bool {
+	re, err := syntax.Parse(s, syntax.Perl)
+	if err != nil {
+		return false
+	}
+	return isNoMatch(re.Simplify())
+}
+
+func isNoMatch(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return true
+	case syntax.OpCharClass:
+		return len(re.Rune) == 0
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if isNoMatch(sub) {
+				return true
+			}
+		}
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !isNoMatch(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpCapture, syntax.OpPlus:
+		return isNoMatch(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min > 0 && isNoMatch(re.Sub[0])
+	}
+	return false
+}
+
+// configCommand checks config files or runs tests of profiles
+func configCommand(args []string) {
+	usage := "kolorit config check [-conf FILE]\n  kolorit config test [-conf FILE] [NAME...]"
+	if len(args) == 0 || (args[0] != "check" && args[0] != "test") {
+		commandUsage(usage)
+	}
+	flags, conf := newCommandFlags("config " + args[0])
+	parseArgs(flags, args[1:])
+
+	config, err := readConfig(*conf)
+	if err != nil {
+		errMessage(err.Error())
+	}
+	if args[0] == "test" {
+		configTest(config, flags.Args())
+		return
+	}
+	if flags.NArg() > 0 {
+		commandUsage(usage)
+	}
+	problems := checkConfig(config)
+	order := make(map[string]int)
+	for i, f := range config.files {
+		order[f.path] = i
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].path != problems[j].path {
+			return order[problems[i].path] < order[problems[j].path]
+		}
+		return problems[i].line < problems[j].line
+	})
+	errs := 0
+	for _, p := range problems {
+		fmt.Println(p)
+		if !p.warning {
+			errs++
+		}
+	}
+	fmt.Printf("%d error(s), %d warning(s) in %s\n", errs, len(problems)-errs, config.describe())
+	if errs > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestCheckProfileValue(t *testing.T) {
+	tests := []struct {
+		k     string
+		v     interface{}
+		valid bool
+	}{
+		{"", int64(1), false},
+		{"b", "error", true},
+		{"br", "red", true},
+		{"br", "unknown", false},
+		{"bzz", "red", false},
+		{"B", true, true},
+		{"B", "yes", false},
+	}
+	for _, tt := range tests {
+		if err := checkProfileValue(tt.k, tt.v); (err == nil) != tt.valid {
+			t.Errorf("checkProfileValue(%q, %v): got %v", tt.k, tt.v, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var spanRegexp = regexp.MustCompile(`^([a-z]+):(\d+)-(\d+)$`)
+
+// profileTest is a test written in [[NAME.tests]] of profile.
+// offsets of spans are counted in characters of input.
+type profileTest struct {
+	input  string
+	expect []span
+}
+
+// profileTests returns tests written in the section itself. they are not inherited with 'extends'.
+func (c *config) profileTests(name string) ([]profileTest, error) {
+	section := c.section(name)
+	if section == nil {
+		return nil, errors.New("'" + name + "' is not defined in " + c.describe())
+	}
+	v, ok := section.values["tests"]
+	if !ok {
+		return nil, nil
+	}
+	return parseTests(v)
+}
+
+func parseTests(v interface{}) ([]profileTest, error) {
+	tables, ok := v.([]map[string]interface{})
+	if !ok {
+		return nil, errors.New("must be an array of tables")
+	}
+	tests := make([]profileTest, 0, len(tables))
+	for i, table := range tables {
+		where := "#" + strconv.Itoa(i+1)
+		for k := range table {
+			if k != "input" && k != "expect" {
+				return nil, errors.New(where + " has unknown key: " + k)
+			}
+		}
+		input, ok := table["input"].(string)
+		if !ok {
+			return nil, errors.New(where + " must have 'input' string")
+		}
+		expect, ok := table["expect"].(string)
+		if !ok {
+			return nil, errors.New(where + " must have 'expect' string")
+		}
+		spans, err := parseSpans(expect)
+		if err != nil {
+			return nil, errors.New(where + " " + err.Error())
+		}
+		tests = append(tests, profileTest{input: input, expect: spans})
+	}
+	return tests, nil
+}
+
+func checkTests(v interface{}) error {
+	_, err := parseTests(v)
+	return err
+}
+
+// parseSpans parses spans written like "y:1-2 b:0-1"
+func parseSpans(s string) ([]span, error) {
+	spans := make([]span, 0)
+	for _, field := range strings.Fields(s) {
+		m := spanRegexp.FindStringSubmatch(field)
+		if m == nil {
+			return nil, errors.New("has wrong span: " + field + " (must be like 'y:1-2')")
+		}
+		if _, ok := colorMap[m[1]]; !ok {
+			return nil, errors.New("has unknown color in span: " + field)
+		}
+		start, _ := strconv.Atoi(m[2])
+		end, _ := strconv.Atoi(m[3])
+		if start >= end {
+			return nil, errors.New("has empty span: " + field)
+		}
+		spans = append(spans, span{start: start, end: end, color: m[1]})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans, nil
+}
+
+func formatSpan(s span) string {
+	return fmt.Sprintf("%s:%d-%d", s.color, s.start, s.end)
+}
+
+func formatSpans(spans []span) string {
+	fields := make([]string, 0, len(spans))
+	for _, s := range spans {
+		fields = append(fields, formatSpan(s))
+	}
+	return strings.Join(fields, " ")
+}
+
+// testSpans returns text after erased with -e and its spans which offsets are counted in characters
+func (kolorit *kolorit) testSpans(input string) (string, []span) {
+	text := kolorit.reErase.ReplaceAllString(input, "")
+	var spans []span
+	if kolorit.asSingle {
+		spans, _ = findSpans(kolorit.re, text)
+	} else {
+		// colored line by line as the same as reading files
+		offset := 0
+		for _, line := range strings.SplitAfter(text, "\n") {
+			lineSpans, _ := findSpans(kolorit.re, strings.TrimSuffix(line, "\n"))
+			for _, s := range lineSpans {
+				spans = append(spans, span{start: offset + s.start, end: offset + s.end, color: s.color})
+			}
+			offset += len(line)
+		}
+	}
+	for i, s := range spans {
+		spans[i].start = utf8.RuneCountInString(text[:s.start])
+		spans[i].end = utf8.RuneCountInString(text[:s.end])
+	}
+	return text, spans
+}
+
+// byteSpans converts offsets of spans in characters to bytes of text
+func byteSpans(text string, spans []span) []span {
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+	converted := make([]span, 0, len(spans))
+	for _, s := range overlapRemoved(append([]span{}, spans...)) {
+		if s.end < len(offsets) {
+			converted = append(converted, span{start: offsets[s.start], end: offsets[s.end], color: s.color})
+		}
+	}
+	return converted
+}
+
+// diffSpans returns spans only in expected and spans only in actual
+func diffSpans(expected []span, actual []span) (missing []span, unexpected []span) {
+	inActual := make(map[span]bool)
+	for _, s := range actual {
+		inActual[s] = true
+	}
+	inExpected := make(map[span]bool)
+	for _, s := range expected {
+		inExpected[s] = true
+		if !inActual[s] {
+			missing = append(missing, s)
+		}
+	}
+	for _, s := range actual {
+		if !inExpected[s] {
+			unexpected = append(unexpected, s)
+		}
+	}
+	return missing, unexpected
+}
+
+// runProfileTests runs tests of profile and prints results. it returns numbers of passed and failed tests.
+func (c *config) runProfileTests(name string, tests []profileTest) (int, int) {
+	p, err := c.effectiveProfile([]string{name})
+	if err != nil {
+		errMessage(err.Error())
+	}
+	k := newKolorit()
+	if k.patterns, err = c.patterns(); err != nil {
+		errMessage(err.Error())
+	}
+	k.applyProfile(p)
+	k.prepare()
+
+	passed, failed := 0, 0
+	for i, test := range tests {
+		text, actual := k.testSpans(test.input)
+		missing, unexpected := diffSpans(test.expect, actual)
+		title := fmt.Sprintf("[%s] #%d %q", name, i+1, test.input)
+		if len(missing) == 0 && len(unexpected) == 0 {
+			passed++
+			fmt.Println("ok   " + title)
+			continue
+		}
+		failed++
+		fmt.Println("FAIL " + title)
+		indent := strings.Repeat(" ", 12)
+		fmt.Println("  expected: " + strings.Replace(k.renderANSI(text, byteSpans(text, test.expect)), "\n", "\n"+indent, -1))
+		fmt.Println("  actual:   " + strings.Replace(k.renderANSI(text, byteSpans(text, actual)), "\n", "\n"+indent, -1))
+		fmt.Println("  expect = " + strconv.Quote(formatSpans(test.expect)))
+		fmt.Println("  actual = " + strconv.Quote(formatSpans(actual)))
+		for _, s := range missing {
+			fmt.Println(k.renderANSI("  - "+formatSpan(s), []span{{start: 2, end: 4 + len(formatSpan(s)), color: "r"}}))
+		}
+		for _, s := range unexpected {
+			fmt.Println(k.renderANSI("  + "+formatSpan(s), []span{{start: 2, end: 4 + len(formatSpan(s)), color: "g"}}))
+		}
+	}
+	return passed, failed
+}
+
+// configTest runs tests of profiles given as args, or of all profiles
+func configTest(config *config, names []string) {
+	if len(names) == 0 {
+		names = config.profileNames()
+	}
+	passed, failed := 0, 0
+	for _, name := range names {
+		tests, err := config.profileTests(name)
+		if err != nil {
+			errMessage("'tests' in [" + name + "] " + err.Error())
+		}
+		if len(tests) == 0 {
+			continue
+		}
+		p, f := config.runProfileTests(name, tests)
+		passed += p
+		failed += f
+	}
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
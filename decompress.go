@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// extensions of compressed files which are read transparently
+var compressedExts = []string{".gz", ".bz2", ".zlib"}
+
+type decompressedFile struct {
+	io.Reader
+	file io.Closer
+}
+
+func (d *decompressedFile) Close() error {
+	return d.file.Close()
+}
+
+// decompress returns reader of decompressed content when content of fp is compressed.
+// compression is detected by magic bytes.
+func decompress(fp io.ReadCloser, name string) (io.ReadCloser, error) {
+	br := bufio.NewReader(fp)
+	magic, _ := br.Peek(10)
+	var r io.Reader
+	var err error
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		r, err = gzip.NewReader(br)
+	case isBzip2(magic):
+		r = bzip2.NewReader(br)
+	case isZlib(magic, name):
+		r, err = zlib.NewReader(br)
+	default:
+		r = br
+	}
+	if err != nil {
+		fp.Close()
+		return nil, err
+	}
+	return &decompressedFile{Reader: r, file: fp}, nil
+}
+
+// isBzip2 checks bzip2 header "BZh1"-"BZh9" and magic of the first block, or end of stream for empty content
+func isBzip2(magic []byte) bool {
+	if len(magic) < 10 || !bytes.HasPrefix(magic, []byte("BZh")) || magic[3] < '1' || magic[3] > '9' {
+		return false
+	}
+	return bytes.Equal(magic[4:10], []byte("1AY&SY")) || bytes.Equal(magic[4:10], []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90})
+}
+
+// isZlib checks zlib header. '0x78 0x5e' is also "x^" in text,
+// so it is regarded as zlib only when file name ends with '.zlib'.
+func isZlib(magic []byte, name string) bool {
+	if len(magic) < 2 || magic[0] != 0x78 || (uint(magic[0])<<8|uint(magic[1]))%31 != 0 {
+		return false
+	}
+	switch magic[1] {
+	case 0x01, 0x9c, 0xda:
+		return true
+	case 0x5e:
+		return strings.ToLower(filepath.Ext(name)) == ".zlib"
+	}
+	return false
+}
+
+// trimCompressedExt removes extension of compressed file.
+// it returns empty string when file has no such extension.
+func trimCompressedExt(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range compressedExts {
+		if ext == e {
+			return name[:len(name)-len(ext)]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	toml "github.com/pelletier/go-toml"
+)
+
+// file types which are ignored unless -ext is given or they are selected with -t.
+// compressed files are also ignored when -nz is given.
+var ignoredFileTypes = []string{"image", "video", "audio", "archive"}
+
+var defaultFileTypes = map[string][]string{
+	"c":          extGlobs("c h"),
+	"compressed": extGlobs("gz bz2 zlib"),
+	"cpp":        extGlobs("cpp cc cxx c++ hpp hh hxx h++"),
+	"css":        extGlobs("css scss sass less"),
+	"go":         extGlobs("go"),
+	"html":       extGlobs("html htm xhtml"),
+	"java":       extGlobs("java"),
+	"js":         extGlobs("js mjs cjs jsx"),
+	"json":       extGlobs("json"),
+	"log":        append(extGlobs("log"), "*.log.[0-9]*"),
+	"make":       append(extGlobs("mk mak"), "makefile", "gnumakefile"),
+	"markdown":   extGlobs("md markdown mdown mkd"),
+	"perl":       extGlobs("pl pm t psgi"),
+	"php":        extGlobs("php"),
+	"py":         extGlobs("py"),
+	"rb":         append(extGlobs("rb"), "gemfile", "rakefile"),
+	"rust":       extGlobs("rs"),
+	"sh":         extGlobs("sh bash zsh"),
+	"sql":        extGlobs("sql"),
+	"toml":       extGlobs("toml"),
+	"ts":         extGlobs("ts tsx"),
+	"txt":        extGlobs("txt"),
+	"xml":        extGlobs("xml xsd xsl"),
+	"yaml":       extGlobs("yml yaml"),
+	// https://en.wikipedia.org/wiki/Image_file_formats#Raster_formats
+	"image": extGlobs("jpg jpeg png gif bmp raw raw2 tif tiff ppm pgm pbm pnm heif heic bpg webp ico psd xcf svg swf pdf ai cgm gbr"),
+	// https://en.wikipedia.org/wiki/Video_file_format
+	"video": extGlobs("webm flv vob ogv ogg drc gifv mng avi mov qt wmv yuv rm rmvb asf amv mp4 m4p m4v mpg mp2 mpv mpe mpeg svi 3g2 3gp mxf roq nsv f4v f4p f4a f4b"),
+	// https://en.wikipedia.org/wiki/Audio_file_format
+	"audio": extGlobs("3gp aa aac aax act aiff amr ape au awb dct dss dvf fla flac gsm iklax m4a m4b m4p mmf mp3 mpc msv ogg oga mogg opus ra rm raw sln tta vox wav wma wv webm"),
+	// https://en.wikipedia.org/wiki/List_of_archive_formats
+	"archive": extGlobs("a ar cpio shar lbr iso mar tar tgz tbz tbz2 lz lzma lzo rz sfark sz xz z 7z s7z ace afa alz apk arc arj b1 ba bh cab car cfs cpt dar dd dgc dmg ear gca ha hki ice jar kgb lzh lha lza pak partimg pag pea pim pit qda rar rk sda sea sen sfx shk si sitx sqx uc uc0 uc2 uca uha war wim xar xp3 yz1 zip zipx zoo zpaq zz"),
+}
+
+// extGlobs makes glob patterns from space separated extensions
+func extGlobs(exts string) []string {
+	globs := make([]string, 0)
+	for _, e := range strings.Fields(exts) {
+		globs = append(globs, "*."+e)
+	}
+	return globs
+}
+
+// parseFileTypes reads [types] section of config file.
+// each key is a type name and its value is a glob or an array of globs.
+// a type which has the same name as a predefined one overrides it.
+func (kolorit *kolorit) parseFileTypes(config *toml.TomlTree, configFile string) {
+	types := config.Get("types")
+	if types == nil {
+		return
+	}
+	tree, ok := types.(*toml.TomlTree)
+	if !ok {
+		errMessage("'types' must be a table in " + configFile)
+	}
+	for _, name := range tree.Keys() {
+		switch v := tree.Get(name).(type) {
+		case string:
+			kolorit.fileTypes[name] = []string{v}
+		case []interface{}:
+			globs := make([]string, 0)
+			for _, g := range v {
+				s, ok := g.(string)
+				if !ok {
+					errMessage("globs of type '" + name + "' must be strings in " + configFile)
+				}
+				globs = append(globs, s)
+			}
+			kolorit.fileTypes[name] = globs
+		default:
+			errMessage("type '" + name + "' must be a glob or an array of globs in " + configFile)
+		}
+	}
+}
+
+// parseTypeNames splits comma separated type names and checks they are defined
+func (kolorit *kolorit) parseTypeNames(names string) []string {
+	types := make([]string, 0)
+	for _, name := range splitNames(names) {
+		if _, ok := kolorit.fileTypes[name]; !ok {
+			errMessage("unknown file type: " + name + " (see -type-list)")
+		}
+		types = append(types, name)
+	}
+	return types
+}
+
+// matchFileTypes returns true when file name matches any glob of the given types.
+// name of compressed file is also matched without its extension(e.g. app.log.gz as app.log).
+func (kolorit *kolorit) matchFileTypes(file string, types []string) bool {
+	names := []string{strings.ToLower(filepath.Base(file))}
+	if trimmed := trimCompressedExt(names[0]); trimmed != "" && !kolorit.options["nz"] {
+		names = append(names, trimmed)
+	}
+	for _, t := range types {
+		for _, glob := range kolorit.fileTypes[t] {
+			for _, name := range names {
+				if matched, _ := filepath.Match(strings.ToLower(glob), name); matched {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (kolorit *kolorit) printFileTypes() {
+	names := make([]string, 0, len(kolorit.fileTypes))
+	for name := range kolorit.fileTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, strings.Join(kolorit.fileTypes[name], ", "))
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// htmlRenderer writes HTML document, or only <pre> element as fragment.
+// spans are elements which have classes of color and style, and file names and line numbers are anchors.
+type htmlRenderer struct {
+	fragment bool
+}
+
+func (h *htmlRenderer) begin(w io.Writer) {
+	if !h.fragment {
+		fmt.Fprintln(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>kolorit</title>")
+		fmt.Fprint(w, "<style>\n"+htmlStyleSheet()+"</style>\n</head>\n<body>\n")
+	}
+	fmt.Fprint(w, `<pre class="kolorit">`)
+}
+
+func (h *htmlRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	var b strings.Builder
+	if name, ln := kolorit.prefixOf(name, ln); name != "" || ln > 0 {
+		id, prefix := "L"+strconv.Itoa(ln), ""
+		if name != "" {
+			id, prefix = name, `<span class="k-file">`+html.EscapeString(name)+`</span><span class="k-sep">:</span>`
+			if ln > 0 {
+				id += ":" + strconv.Itoa(ln)
+			}
+		}
+		if ln > 0 {
+			prefix += `<span class="k-ln">` + strconv.Itoa(ln) + `</span><span class="k-sep">:</span>`
+		}
+		fmt.Fprintf(&b, `<a id="%s" href="%s">%s</a>`, html.EscapeString(id), html.EscapeString((&url.URL{Fragment: id}).String()), prefix)
+	}
+	last := 0
+	for _, s := range spans {
+		b.WriteString(html.EscapeString(text[last:s.start]))
+		fmt.Fprintf(&b, `<span class="%s">%s</span>`, htmlClasses(kolorit.styleOf(s.color)), html.EscapeString(text[s.start:s.end]))
+		last = s.end
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	fmt.Fprintln(w, b.String())
+}
+
+func (h *htmlRenderer) end(w io.Writer) {
+	fmt.Fprintln(w, "</pre>")
+	if !h.fragment {
+		fmt.Fprintln(w, "</body>\n</html>")
+	}
+}
+
+// htmlClasses returns classes of style. "k-r" is for color of -r, and "k-bg-b" is for background of blue.
+func htmlClasses(s style) string {
+	classes := []string{"k-" + s.color}
+	if s.bg != "" {
+		classes = append(classes, "k-bg-"+s.bg)
+	}
+	for _, c := range []struct {
+		on    bool
+		class string
+	}{{s.bold, "k-bold"}, {s.underline, "k-underline"}, {s.inverse, "k-inverse"}} {
+		if c.on {
+			classes = append(classes, c.class)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// htmlStyleSheet returns CSS for classes of colors and styles
+func htmlStyleSheet() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pre.kolorit { background: %s; color: %s; padding: 1em; }\n", rgbBackground, rgbForeground)
+	b.WriteString("pre.kolorit a { color: inherit; text-decoration: none; }\n")
+	fmt.Fprintf(&b, ".k-file { color: %s; }\n.k-ln { color: %s; }\n.k-sep { color: %s; }\n", rgbColors["purple"], rgbColors["yellow"], rgbColors["cyan"])
+	for _, c := range colorArray {
+		fmt.Fprintf(&b, ".k-%s { color: %s; }\n", c.s, rgbColors[c.l])
+	}
+	for _, c := range colorArray {
+		fmt.Fprintf(&b, ".k-bg-%s { background: %s; }\n", c.s, rgbColors[c.l])
+	}
+	b.WriteString(".k-bold { font-weight: bold; }\n.k-underline { text-decoration: underline; }\n")
+	for _, c := range colorArray {
+		fmt.Fprintf(&b, ".k-inverse.k-%s { color: %s; background: %s; }\n", c.s, rgbBackground, rgbColors[c.l])
+	}
+	return b.String()
+}
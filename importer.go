@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// colors of grc and their short names in kolorit
+var grcColors = map[string]string{
+	"black": "k", "red": "r", "green": "g", "yellow": "y", "blue": "b", "magenta": "p", "cyan": "c", "white": "w",
+	"bright_black": "dgr", "bright_red": "lr", "bright_green": "lg", "bright_yellow": "ly",
+	"bright_blue": "lb", "bright_magenta": "lp", "bright_cyan": "lc", "bright_white": "w",
+}
+
+// attributes of grc and kolorit options for them
+var grcAttributes = map[string]string{"bold": "B", "underline": "U", "reverse": "I"}
+
+var pythonNamedGroupRegexp = regexp.MustCompile(`\(\?P<\w+>`)
+
+// grcRule is an entry of grc config file
+type grcRule struct {
+	line   int
+	values map[string]string
+}
+
+// grcStyle is a style converted from colours of grc
+type grcStyle struct {
+	color string
+	bg    string
+	// attributes(bold, underline, reverse)
+	attrs []string
+	// numbers of groups which are colored. nil means whole match is colored
+	groups []int
+}
+
+// readGrcRules reads entries of grc config file. entries are separated by lines which don't start with letters.
+func readGrcRules(r io.Reader) ([]grcRule, error) {
+	rules := make([]grcRule, 0)
+	var rule *grcRule
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if !unicode.IsLetter(rune(line[0])) {
+			rule = nil
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if rule == nil {
+			rules = append(rules, grcRule{line: n, values: make(map[string]string)})
+			rule = &rules[len(rules)-1]
+		}
+		rule.values[strings.TrimSpace(kv[0])] = kv[1]
+	}
+	return rules, scanner.Err()
+}
+
+// parseGrcColours converts colours of grc rule like "default,bold red,green" into style.
+// the first colour is for whole match and the rest are for groups, and colours of groups take precedence.
+// kolorit cannot color groups with different colors, so the first color of groups is used for all of them.
+func parseGrcColours(colours string) (grcStyle, []string) {
+	var style grcStyle
+	notes := make([]string, 0)
+	items := strings.Split(colours, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		words := strings.Fields(strings.Trim(items[i], `"' `))
+		if i > 0 && len(words) == 0 {
+			continue
+		}
+		item := grcStyle{}
+		for _, w := range words {
+			if c, ok := grcColors[w]; ok {
+				item.color = c
+			} else if c, ok := grcColors[strings.TrimPrefix(w, "on_")]; ok && strings.HasPrefix(w, "on_") {
+				item.bg = colorMap[c]
+			} else if _, ok := grcAttributes[w]; ok {
+				item.attrs = append(item.attrs, w)
+			} else if w != "default" && w != "unchanged" && w != "blink" && w != "concealed" && w != "dark" && w != "italic" {
+				notes = append(notes, "colour '"+w+"' is not supported")
+			}
+		}
+		if item.color == "" || (i == 0 && style.color != "") {
+			continue
+		}
+		if i > 0 {
+			if style.color != "" && style.color != item.color {
+				notes = append(notes, "colours of groups are merged into "+colorMap[item.color])
+			}
+			item.groups = append([]int{i}, style.groups...)
+		}
+		style = item
+	}
+	return style, notes
+}
+
+// keepGroups changes capturing groups of regexp other than given ones into non-capturing groups
+func keepGroups(re string, keep []int) string {
+	kept := make(map[int]bool)
+	for _, n := range keep {
+		kept[n] = true
+	}
+	var b strings.Builder
+	n := 0
+	inClass := false
+	for i := 0; i < len(re); i++ {
+		c := re[i]
+		switch {
+		case c == '\\' && i+1 < len(re):
+			b.WriteString(re[i : i+2])
+			i++
+			continue
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+			// ']' just after '[' or '[^' is a literal
+			if strings.HasPrefix(re[i+1:], "^") {
+				b.WriteByte('^')
+				i++
+			}
+			if strings.HasPrefix(re[i+1:], "]") {
+				b.WriteByte(']')
+				i++
+			}
+			continue
+		case c == '(' && !strings.HasPrefix(re[i+1:], "?"):
+			n++
+			if !kept[n] {
+				b.WriteString("(?:")
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// grcProfile converts rules of grc into values of kolorit profile
+func grcProfile(rules []grcRule) (map[string]interface{}, map[string]string, []string) {
+	regexps := make(map[string][]string)
+	lines := make(map[string][]string)
+	attrs := make(map[string]int)
+	values := make(map[string]interface{})
+	notes := make([]string, 0)
+	colored := 0
+	var previous grcStyle
+	for _, rule := range rules {
+		where := "line " + strconv.Itoa(rule.line) + ": "
+		re := pythonNamedGroupRegexp.ReplaceAllString(rule.values["regexp"], "(")
+		if re == "" {
+			continue
+		}
+		if _, err := regexp.Compile(re); err != nil {
+			notes = append(notes, where+"skipped. regexp is not supported: "+err.Error())
+			continue
+		}
+		for _, k := range []string{"replace", "concat", "command"} {
+			if _, ok := rule.values[k]; ok {
+				notes = append(notes, where+k+" is not supported")
+			}
+		}
+		if v := strings.TrimSpace(rule.values["skip"]); v == "yes" || v == "1" || v == "true" {
+			// kolorit cannot remove lines which are matched
+			notes = append(notes, where+"skipped. skip=yes is not supported")
+			continue
+		}
+
+		style, colourNotes := parseGrcColours(rule.values["colours"])
+		for _, note := range colourNotes {
+			notes = append(notes, where+note)
+		}
+		// kolorit colors all matches of regexps in a line, so only 'previous' is meaningful
+		switch count := strings.TrimSpace(rule.values["count"]); count {
+		case "", "more":
+		case "previous":
+			style = previous
+		default:
+			notes = append(notes, where+"count="+count+" is treated as count=more")
+		}
+		previous = style
+		if style.color == "" {
+			continue
+		}
+
+		re = keepGroups(re, style.groups)
+		regexps[style.color] = append(regexps[style.color], re)
+		lines[style.color] = append(lines[style.color], strconv.Itoa(rule.line))
+		if bg := "b" + style.color; style.bg != "" && values[bg] == nil {
+			values[bg] = style.bg
+		}
+		colored++
+		for _, a := range style.attrs {
+			attrs[a]++
+		}
+	}
+
+	comments := make(map[string]string)
+	for color, res := range regexps {
+		if len(res) == 1 {
+			values[color] = res[0]
+		} else {
+			values[color] = "(?:" + strings.Join(res, ")|(?:") + ")"
+		}
+		comments[color] = "from line " + strings.Join(lines[color], ", ")
+	}
+	// attributes are options of whole profile in kolorit
+	for _, attr := range []string{"bold", "underline", "reverse"} {
+		if n := attrs[attr]; n > 0 && n == colored {
+			values[grcAttributes[attr]] = true
+		} else if n > 0 {
+			notes = append(notes, attr+" is ignored because not all colored rules have it")
+		}
+	}
+	return values, comments, notes
+}
+
+// importCommand converts configurations of other tools into kolorit profiles
+func importCommand(args []string) {
+	usage := "kolorit import grc [-name NAME] FILE\n  kolorit import grep [-name NAME] [PATTERN]"
+	if len(args) == 0 {
+		commandUsage(usage)
+	}
+	switch args[0] {
+	case "grc":
+		importGrc(args[1:], usage)
+	case "grep":
+		importGrep(args[1:], usage)
+	default:
+		commandUsage(usage)
+	}
+}
+
+func importGrc(args []string, usage string) {
+	flags, _ := newCommandFlags("import grc")
+	name := flags.String("name", "", "name of profile. default is name of FILE without 'conf.'")
+	parseArgs(flags, args)
+	if flags.NArg() != 1 {
+		commandUsage(usage)
+	}
+	file := flags.Arg(0)
+	fp, err := os.Open(file)
+	errCheck(err, "cannot open file: "+file)
+	rules, err := readGrcRules(fp)
+	errCheck(err, "error on reading file: "+file)
+	errCheck(fp.Close(), "error on closing file: "+file)
+
+	command := strings.TrimPrefix(filepath.Base(file), "conf.")
+	if *name == "" {
+		*name = command
+	}
+	values, comments, notes := grcProfile(rules)
+	values["match_command"] = command
+	fmt.Println("# imported from " + file + " with kolorit import grc")
+	for _, note := range notes {
+		fmt.Println("# " + note)
+	}
+	writeTOML(os.Stdout, tomlKey(*name), values, comments)
+}
+
+// importGrep converts style of matched string in GREP_COLORS or GREP_COLOR into [default].
+// when PATTERN is given, it is colored with color option of foreground in a profile(default is [grep]).
+func importGrep(args []string, usage string) {
+	flags, _ := newCommandFlags("import grep")
+	name := flags.String("name", "", "name of profile. default is 'grep' with PATTERN, 'default' without it")
+	parseArgs(flags, args)
+	if flags.NArg() > 1 {
+		commandUsage(usage)
+	}
+	pattern := flags.Arg(0)
+	if *name == "" {
+		*name = "default"
+		if pattern != "" {
+			*name = "grep"
+		}
+	}
+
+	sgr, from := "01;31", "default of grep"
+	if v := os.Getenv("GREP_COLOR"); v != "" {
+		sgr, from = v, "GREP_COLOR="+v
+	}
+	if colors := os.Getenv("GREP_COLORS"); colors != "" {
+		caps := make(map[string]string)
+		for _, c := range strings.Split(colors, ":") {
+			kv := strings.SplitN(c, "=", 2)
+			if len(kv) == 2 {
+				caps[kv[0]] = kv[1]
+			}
+		}
+		for _, k := range []string{"mt", "ms"} {
+			if v, ok := caps[k]; ok {
+				sgr, from = v, "GREP_COLORS="+colors
+			}
+		}
+	}
+
+	values := make(map[string]interface{})
+	comments := make(map[string]string)
+	notes := make([]string, 0)
+	codes := strings.Split(sgr, ";")
+	for i := 0; i < len(codes); i++ {
+		n, err := strconv.Atoi(codes[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case n == 1:
+			values["B"] = true
+		case n == 4:
+			values["U"] = true
+		case n == 7:
+			values["I"] = true
+		case n >= 30 && n <= 37, n >= 90 && n <= 97:
+			fg := sgrColors[n%10]
+			if n >= 90 {
+				fg = brightSgrColors[n%10]
+			}
+			if pattern != "" {
+				values[fg] = pattern
+				comments[fg] = "matched string of grep"
+			} else {
+				notes = append(notes, "color of matched string is given by -"+fg+" in kolorit(e.g. kolorit -"+fg+" PATTERN)")
+			}
+		case n >= 40 && n <= 47, n >= 100 && n <= 107:
+			bg := sgrColors[n%10]
+			if n >= 100 {
+				bg = brightSgrColors[n%10]
+			}
+			for _, k := range colorNames {
+				values["b"+k] = colorMap[bg]
+				comments["b"+k] = "background of matched string"
+			}
+		case n == 38 || n == 48:
+			notes = append(notes, "256 colors and true colors are not supported")
+			i = len(codes)
+		}
+	}
+	fmt.Println("# imported from " + from + " with kolorit import grep")
+	for _, note := range uniqStrings(notes) {
+		fmt.Println("# " + note)
+	}
+	writeTOML(os.Stdout, tomlKey(*name), values, comments)
+}
+
+// short color names in order of SGR color codes
+var sgrColors = []string{"k", "r", "g", "y", "b", "p", "c", "w"}
+var brightSgrColors = []string{"dgr", "lr", "lg", "ly", "lb", "lp", "lc", "w"}
+
+func uniqStrings(list []string) []string {
+	seen := make(map[string]bool)
+	uniq := make([]string, 0, len(list))
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			uniq = append(uniq, s)
+		}
+	}
+	return uniq
+}
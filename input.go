@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// file name which means STDIN
+const stdinName = "-"
+
+var errIsDir = errors.New("is a directory")
+
+var errIsTar = errors.New("tar archive is read only with -archives")
+
+// openFile opens file to read. '-' means STDIN.
+// compressed file is decompressed unless -nz is given. tar archive is not read as text.
+func (kolorit *kolorit) openFile(name string) (io.ReadCloser, error) {
+	if name == stdinName {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, errIsDir
+	}
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	var r io.ReadCloser = fp
+	if !kolorit.options["nz"] {
+		if r, err = decompress(fp, name); err != nil {
+			return nil, err
+		}
+	}
+	br := bufio.NewReader(r)
+	if isTar(br) {
+		r.Close()
+		return nil, errIsTar
+	}
+	return &decompressedFile{Reader: br, file: r}, nil
+}
+
+// readFileNames reads file names separated by sep
+func readFileNames(r io.Reader, sep byte) ([]string, error) {
+	names := make([]string, 0)
+	reader := bufio.NewReader(r)
+	for {
+		name, err := reader.ReadString(sep)
+		name = strings.TrimSuffix(name, string(sep))
+		if sep == '\n' {
+			name = strings.TrimSuffix(name, "\r")
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+		if err == io.EOF {
+			return names, nil
+		} else if err != nil {
+			return names, err
+		}
+	}
+}
+
+// readFilesFrom adds file names given with -files-from
+func (kolorit *kolorit) readFilesFrom(from string) {
+	var sep byte = '\n'
+	if kolorit.options["0"] {
+		sep = 0
+	}
+	fp, err := kolorit.openFile(from)
+	errCheck(err, "cannot open file: "+from)
+	names, err := readFileNames(fp, sep)
+	errCheck(err, "error on reading file names: "+from)
+	errCheck(fp.Close(), "error on closing file: "+from)
+	if isDebug {
+		log.Println("Files From " + from + ": " + strings.Join(names, ", "))
+	}
+	kolorit.files = append(kolorit.files, names...)
+}
+
+// displayName returns name of file to be shown
+func displayName(name string) string {
+	if name == stdinName {
+		return "(standard input)"
+	}
+	return name
+}
+
+// readInput colors content of file with profile selected for it
+func (kolorit *kolorit) readInput(r io.Reader, name string) {
+	br := bufio.NewReaderSize(r, 4096)
+	k := kolorit.forInput(name, br)
+	k.sanitizing = k.sanitizes(name != stdinName)
+	if k.asSingle {
+		k.readWhole(br, k.sanitizedName(displayName(name)))
+	} else {
+		k.readLines(br, k.sanitizedName(displayName(name)))
+	}
+}
+
+// readWhole colors whole content of file at once
+func (kolorit *kolorit) readWhole(r io.Reader, name string) {
+	whole, err := ioutil.ReadAll(r)
+	if err != nil {
+		log.Println(err.Error() + ":error on reading file: " + name)
+		return
+	}
+	text, spans, _, e := kolorit.matchText(kolorit.re, kolorit.reErase, kolorit.sanitized(string(whole)))
+	if e != nil {
+		log.Println(e.Error() + " : " + name)
+		return
+	}
+	kolorit.output(text, spans, name, 0)
+}
+
+// readLines colors content of file line by line
+func (kolorit *kolorit) readLines(r io.Reader, name string) {
+	reader := bufio.NewReaderSize(r, 4096)
+	lineNumber := 0
+	for {
+		lineNumber++
+		line, ioerr := readLine(reader)
+		if ioerr != nil && ioerr != io.EOF {
+			log.Println(ioerr.Error() + " :error on reading file content: " + name)
+			break
+		} else if ioerr == io.EOF {
+			break
+		}
+
+		text, spans, show, e := kolorit.matchLine(line)
+		if e != nil {
+			log.Println(e.Error() + " : " + name)
+			break
+		}
+		if show {
+			kolorit.output(text, spans, name, lineNumber)
+		}
+	}
+}
+
+// readLine reads a line without end of line. a line longer than buffer of reader is read as a line.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, isPrefix, err := reader.ReadLine()
+	if !isPrefix || err != nil {
+		return string(line), err
+	}
+	long := append([]byte{}, line...)
+	for isPrefix && err == nil {
+		line, isPrefix, err = reader.ReadLine()
+		long = append(long, line...)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return string(long), err
+}
+
+// matchLine returns spans of a line and whether it is shown. lines not matched are not shown with -grep.
+func (kolorit *kolorit) matchLine(line string) (string, []span, bool, error) {
+	line = kolorit.sanitized(line)
+	text, spans, n, err := kolorit.matchText(kolorit.re, kolorit.reErase, line)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if kolorit.options["grep"] {
+		return text, spans, (!kolorit.options["and"] || n == kolorit.numOfRegexps) && (len(spans) > 0 || text != line), nil
+	}
+	return text, spans, true, nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadLine(t *testing.T) {
+	long := strings.Repeat("a", 5000)
+	tests := []struct {
+		name  string
+		input string
+		lines []string
+	}{
+		{"short lines", "a\nb\r\nc", []string{"a", "b", "c"}},
+		{"line longer than buffer", long + "\nb\n", []string{long, "b"}},
+		{"long line without newline", "b\n" + long, []string{"b", long}},
+		{"empty lines", "\n\n", []string{"", ""}},
+	}
+	for _, tt := range tests {
+		reader := bufio.NewReaderSize(strings.NewReader(tt.input), 4096)
+		lines := make([]string, 0)
+		for {
+			line, err := readLine(reader)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			lines = append(lines, line)
+		}
+		if strings.Join(lines, "|") != strings.Join(tt.lines, "|") || len(lines) != len(tt.lines) {
+			t.Errorf("%s: got %d lines, want %d lines", tt.name, len(lines), len(tt.lines))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonRenderer writes an object for each matched line in a line(NDJSON)
+type jsonRenderer struct{}
+
+type jsonLine struct {
+	File string `json:"file"`
+	// line number. it is omitted when whole content is read with -s
+	Line  int        `json:"line,omitempty"`
+	Text  string     `json:"text"`
+	Spans []jsonSpan `json:"spans"`
+}
+
+type jsonSpan struct {
+	// byte offsets in text
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Rule  string `json:"rule"`
+	Color string `json:"color"`
+	Text  string `json:"text"`
+	// severity and message of rule declared in profile
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+func (jsonRenderer) begin(w io.Writer) {}
+
+func (jsonRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	if len(spans) == 0 {
+		return
+	}
+	line := jsonLine{File: name, Line: ln, Text: text, Spans: make([]jsonSpan, 0, len(spans))}
+	for _, s := range spans {
+		r := kolorit.rules[s.color]
+		line.Spans = append(line.Spans, jsonSpan{
+			Start: s.start, End: s.end, Rule: s.color, Color: colorMap[s.color], Text: text[s.start:s.end],
+			Severity: r.severity, Message: r.message,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	errCheck(encoder.Encode(line), "error on writing JSON")
+}
+
+func (jsonRenderer) end(w io.Writer) {}
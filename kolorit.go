@@ -1,39 +1,63 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/ktat/go-ansistrings"
 	"github.com/mitchellh/go-homedir"
-	toml "github.com/pelletier/go-toml"
 )
 
 var isDebug bool
 
 type kolorit struct {
 	strOptions   map[string]string
+	intOptions   map[string]int
+	cliOptions   map[string]bool
 	options      map[string]bool
+	regexps      map[string]string
+	bgColors     map[string]string
 	bg           map[string]int
 	pattern      string
 	erasePattern string
+	re           *regexp.Regexp
+	reErase      *regexp.Regexp
 	numOfRegexps int
 	files        []string
 	fileName     string
 	isRecursive  bool
 	fromSTDIN    bool
 	asSingle     bool
+	fileTypes    map[string][]string
+	selectTypes  []string
+	excludeTypes []string
+	maxFileSize  int64
+	newerThan    time.Time
+	sortKey      string
+	sortReverse  bool
+	showFileName bool
+	config       *config
+	base         *kolorit
+	autoProfiles []*autoProfile
+	patterns     map[string]string
+	selected     map[string]*kolorit
+	// command and its arguments to run in wrapper mode
+	wrapped []string
+	// name of color for text which is not colored
+	tint     string
+	renderer renderer
+	// severities and messages of regexps of colors
+	rules map[string]rule
+	// whether control sequences of input being read are neutralized
+	sanitizing bool
 }
 
 type optDef struct {
@@ -41,8 +65,10 @@ type optDef struct {
 	order    int
 	isBool   bool
 	isString bool
+	isInt    bool
 	boolDef  bool
 	strDef   string
+	intDef   int
 	help     string
 }
 
@@ -54,11 +80,13 @@ type colorName struct {
 var opt []optDef
 var homeDir string
 var homeDirRegexp *regexp.Regexp
-var resetRegexp = regexp.MustCompile("(?m)^(\\033\\[0m)?")
 var colorArray []colorName
 var colorMap = make(map[string]string)
 var colorNames []string
 
+// short color names of named groups in regexp built by prepare
+var colorOfGroup = make(map[string]string)
+
 func init() {
 	var err error
 	homeDir, err = homedir.Dir()
@@ -72,24 +100,54 @@ func init() {
 	opt = []optDef{
 		optDef{k: "help", isBool: true, boolDef: false, help: "show usage"},
 		optDef{k: "h", isBool: true, boolDef: false, help: "show usage"},
-		optDef{k: "conf", isString: true, strDef: homeDir + ".kolorit.toml", help: "path of config file"},
-		optDef{k: "use", isString: true, strDef: "", help: "use predefined setting from config file($HOME/.kolorit.toml)"},
+		optDef{k: "conf", isString: true, strDef: "", help: "path of config file. only the file is read when it is given(see 'Config file' in README)"},
+		optDef{k: "show-config-sources", isBool: true, boolDef: false, help: "show config files and which file sets each value, and exit"},
+		optDef{k: "use", isString: true, strDef: "", help: "use predefined setting from config file. comma separated names are merged in order"},
+		optDef{k: "save-profile", isString: true, strDef: "", help: "save options given in command line as a profile of given name in config file, and exit"},
+		optDef{k: "auto", isBool: true, boolDef: true, help: "select profile by match_files, match_command and match_content of profiles when -use, regexps of colors and -e are not given"},
+		optDef{k: "auto-builtin", isBool: true, boolDef: false, help: "select built-in profiles automatically too with -auto"},
+		optDef{k: "command", isString: true, strDef: "", help: "name of command which output is given from STDIN. used with match_command of profiles"},
+		optDef{k: "run", isBool: true, boolDef: false, help: "run arguments after -- as command even if the first of them is a file"},
+		optDef{k: "pty", isBool: true, boolDef: true, help: "run command with pseudo terminal when stdout or stderr is a terminal"},
+		optDef{k: "stderr-color", isString: true, strDef: "red", help: "color of text in stderr of command which is not colored by regexps. 'none' not to color it"},
+		optDef{k: "format", isString: true, strDef: "ansi", help: "output format(" + formatNames() + ")"},
 		optDef{k: "grep", isBool: true, boolDef: false, help: "take string and ignore not matched lines with it like grep. cannot use it with -s"},
 		optDef{k: "and", isBool: true, boolDef: false, help: "change grep option behavior. take string only when all regexps are matched."},
 		optDef{k: "ngrep", isBool: true, boolDef: false, help: "ignore grep option"},
 		optDef{k: "s", isBool: true, boolDef: false, help: "regexp option. treat given content as single line(default as multi line)"},
 		optDef{k: "i", isBool: true, boolDef: false, help: "regexp option. do case insensitive pattern matching."},
 		optDef{k: "R", isBool: true, boolDef: false, help: "recursively read directory."},
+		optDef{k: "max-depth", isInt: true, intDef: 0, help: "descend at most given levels of directories. 0 means no limit"},
+		optDef{k: "L", isBool: true, boolDef: false, help: "follow symbolic links of directories."},
+		optDef{k: "max-filesize", isString: true, strDef: "", help: "ignore files larger than given size(e.g. 512K, 10M, 1G)"},
+		optDef{k: "newer", isString: true, strDef: "", help: "read only files modified more recently than given file"},
+		optDef{k: "changed-within", isString: true, strDef: "", help: "read only files modified within given duration(e.g. 30m, 2h, 7d)"},
+		optDef{k: "sort", isString: true, strDef: "", help: "sort files found in directories by path(default), name, size, mtime or none"},
+		optDef{k: "sortr", isString: true, strDef: "", help: "sort files found in directories in reverse order. same keys as -sort"},
 		optDef{k: "f", isString: true, strDef: "", help: "file pattern. read from matched file."},
+		optDef{k: "files-from", isString: true, strDef: "", help: "read names of files from given file. '-' means STDIN"},
+		optDef{k: "0", isBool: true, boolDef: false, help: "names of files given with -files-from are separated by NUL character"},
 		optDef{k: "e", isString: true, strDef: "", help: "erase matched string"},
+		optDef{k: "sanitize", isBool: true, boolDef: false, help: "neutralize control sequences in input of files too. input from STDIN and commands is always neutralized"},
+		optDef{k: "nsanitize", isBool: true, boolDef: false, help: "ignore -sanitize option and don't neutralize control sequences in input from STDIN and commands"},
+		optDef{k: "allow-sgr", isBool: true, boolDef: false, help: "keep SGR sequences(colors) in input which is neutralized. they are always kept in output of commands"},
+		optDef{k: "strip-ansi", isBool: true, boolDef: false, help: "remove escape sequences(e.g. colors) in input. colors in input are kept and regexps are matched with text without them by default"},
 		optDef{k: "B", isBool: true, boolDef: false, help: "matched string to be bold"},
 		optDef{k: "nB", isBool: true, boolDef: false, help: "ignore -B option"},
 		optDef{k: "I", isBool: true, boolDef: false, help: "matched string background color to be inverted"},
 		optDef{k: "nI", isBool: true, boolDef: false, help: "ignore -I option"},
+		optDef{k: "U", isBool: true, boolDef: false, help: "matched string to be underlined"},
+		optDef{k: "nU", isBool: true, boolDef: false, help: "ignore -U option"},
 		optDef{k: "dot", isBool: true, boolDef: false, help: "dot includes files starts with '.'"},
 		optDef{k: "vcs", isBool: true, boolDef: false, help: "vcs includes vcs files/dirs"},
 		optDef{k: "ext", isBool: true, boolDef: false, help: "ext includes predefined extensions to ignore(images,movies,audios etc.)"},
-		optDef{k: "force", isBool: true, boolDef: false, help: "forcely read file even if file has not utf-8 string"},
+		optDef{k: "t", isString: true, strDef: "", help: "file types to read. comma separated(e.g. go,js). see -type-list"},
+		optDef{k: "T", isString: true, strDef: "", help: "file types to ignore. comma separated(e.g. log,markdown)"},
+		optDef{k: "type-list", isBool: true, boolDef: false, help: "show file types and exit"},
+		optDef{k: "pattern-list", isBool: true, boolDef: false, help: "show patterns which can be used as {{name}} in regexps and exit"},
+		optDef{k: "archives", isBool: true, boolDef: false, help: "read members of tar(.tar.gz, .tar.bz2) and zip archives as files"},
+		optDef{k: "nz", isBool: true, boolDef: false, help: "don't decompress gzip, bzip2 and zlib files"},
+		optDef{k: "force", isBool: true, boolDef: false, help: "forcely read file even if file has not utf-8 string. overwrite existing profile with -save-profile"},
 		optDef{k: "d", isBool: true, boolDef: false, help: "debug mode"},
 	}
 
@@ -115,6 +173,7 @@ func init() {
 	for _, v := range colorArray {
 		colorNames = append(colorNames, v.s)
 		colorMap[v.s] = v.l
+		colorOfGroup[v.l] = v.s
 	}
 }
 
@@ -124,6 +183,11 @@ func usage() {
   kolorit [options] [FILES]
   kolorit [options] -f "*.go"
   kolorit [options] -R [FILES/DIRECTORIES]
+  kolorit [options] -- COMMAND [ARGS]
+
+  options can be given after FILES and as --option. arguments after -- are not options.
+  when the first argument after -- is not a file, COMMAND is run and its stdout and stderr are colored.
+  options in KOLORIT_OPTS environment variable are read before them.
 
 Options:
 `)
@@ -136,6 +200,8 @@ Options:
 			} else {
 				fmt.Printf("  -%s\t%s\n", k, v.help)
 			}
+		} else if v.isInt {
+			fmt.Printf("  -%s int\n   \t%s\n", k, v.help)
 		} else {
 			fmt.Printf("  -%s string\n   \t%s\n", k, v.help)
 		}
@@ -169,64 +235,22 @@ func errMessage(e string) {
 	os.Exit(1)
 }
 
-func readStdin(in chan string) {
-	scanner := bufio.NewScanner(os.Stdin)
-
-	for scanner.Scan() {
-		var s = scanner.Text()
-		in <- s
-	}
-	close(in)
-}
-
 func main() {
-	kolorit := kolorit{
-		options:    make(map[string]bool),
-		strOptions: make(map[string]string),
-		bg:         make(map[string]int),
-		files:      make([]string, 0),
+	if command, ok := commandOf(os.Args[1:]); ok {
+		command(os.Args[2:])
+		os.Exit(0)
 	}
-	kolorit.parseOptions()
-
-	re, regexpErr := regexp.Compile(kolorit.pattern)
-	errCheck(regexpErr, "wrong regexp: "+kolorit.pattern)
-
-	reErase, eraseRegexpErr := regexp.Compile(kolorit.erasePattern)
-	errCheck(eraseRegexpErr, "wrong regexp: "+kolorit.erasePattern)
 
-	var ioerr error
+	kolorit := newKolorit()
+	kolorit.parseOptions()
 
-	if kolorit.fromSTDIN {
+	kolorit.renderer.begin(os.Stdout)
+	if kolorit.wrapped != nil {
+		// run command and color its output
+		kolorit.runWrapped()
+	} else if kolorit.fromSTDIN {
 		// read from STDIN
-		if kolorit.asSingle {
-			whole, ioerr := ioutil.ReadAll(os.Stdin)
-			errCheck(ioerr, "error on reading STDIN")
-			str, _, e := kolorit.coloringText(re, reErase, string(whole))
-			if e != nil {
-				errCheck(e)
-			}
-			fmt.Println(str)
-		} else {
-			in := make(chan string)
-			go readStdin(in)
-			// read from STDIN with channel
-			for {
-				l, ok := <-in
-				if ok == false {
-					break
-				} else {
-					colored, n, e := kolorit.coloringText(re, reErase, l)
-					if e != nil {
-						errCheck(e)
-					}
-					if kolorit.options["grep"] && (!kolorit.options["and"] || n == kolorit.numOfRegexps) && colored != string(l) {
-						fmt.Println(colored)
-					} else if !kolorit.options["grep"] {
-						fmt.Println(colored)
-					}
-				}
-			}
-		}
+		kolorit.readInput(os.Stdin, stdinName)
 	} else {
 		// read from file or dir
 		if len(kolorit.files) == 0 {
@@ -240,122 +264,58 @@ func main() {
 			log.Printf("Is Recursive: %t\n", kolorit.isRecursive)
 		}
 
-		if kolorit.asSingle {
-			var whole []byte
-
-			for i := 0; i < len(kolorit.files); i++ {
-				fi, err := os.Stat(kolorit.files[i])
-				if err != nil {
-					log.Println(err.Error() + ":error on stat file: " + kolorit.files[i])
-					continue
-				}
-				if fi.IsDir() {
-					continue
-				}
-				whole, ioerr = ioutil.ReadFile(kolorit.files[i])
-				if ioerr != nil {
-					log.Println(ioerr.Error() + ":error on reading file: " + kolorit.files[i])
-					continue
-				}
-				colored, _, e := kolorit.coloringText(re, reErase, string(whole))
-				if e != nil {
-					log.Println(e.Error() + " : " + kolorit.files[i])
-					continue
-				}
-				kolorit.printColored(colored, i, 0)
-
+		kolorit.showFileName = len(kolorit.files) > 1 || kolorit.options["archives"]
+		for i := 0; i < len(kolorit.files); i++ {
+			if kolorit.options["archives"] && kolorit.readArchive(kolorit.files[i]) {
+				continue
 			}
-		} else {
-			for i := 0; i < len(kolorit.files); i++ {
-				fi, err := os.Stat(kolorit.files[i])
-				if err != nil {
-					if isDebug {
-						log.Println(err.Error() + " : " + kolorit.files[i])
-					}
-					continue
-				}
-				if kolorit.isRecursive && fi.IsDir() {
-					continue
-				}
-				var fp *os.File
-				fp, ioerr = os.Open(kolorit.files[i])
-				if ioerr != nil {
-					log.Println(ioerr.Error() + " :cannot open file: " + kolorit.files[i])
-					continue
-				}
-				reader := bufio.NewReaderSize(fp, 4096)
-				lineNumber := 0
-				for {
-					lineNumber++
-					line, _, ioerr := reader.ReadLine()
-					if ioerr != nil && ioerr != io.EOF {
-						log.Println(ioerr.Error() + " :error on reading file content: " + kolorit.files[i])
-						break
-					} else if ioerr == io.EOF {
-						break
-					}
-
-					colored, n, e := kolorit.coloringText(re, reErase, string(line))
-					if e != nil {
-						log.Println(e.Error() + " : " + kolorit.files[i])
-						break
-					}
-					if kolorit.options["grep"] && (!kolorit.options["and"] || n == kolorit.numOfRegexps) && colored != string(line) {
-						kolorit.printColored(colored, i, lineNumber)
-					} else if !kolorit.options["grep"] {
-						kolorit.printColored(colored, i, lineNumber)
-
-					}
-				}
-				ioerr = fp.Close()
-				errCheck(ioerr, "error on closing file: "+kolorit.files[i])
+			fp, err := kolorit.openFile(kolorit.files[i])
+			if err == errIsDir {
+				continue
+			} else if err != nil {
+				log.Println(err.Error() + " :cannot open file: " + kolorit.files[i])
+				continue
 			}
+			kolorit.readInput(fp, kolorit.files[i])
+			errCheck(fp.Close(), "error on closing file: "+kolorit.files[i])
 		}
 	}
+	kolorit.renderer.end(os.Stdout)
 	os.Exit(0)
 }
 
-func (kolorit *kolorit) printColored(colored string, i int, ln int) {
-	if len(kolorit.files) == 0 {
-		fmt.Println(colored)
-	} else if len(kolorit.files) == 1 {
-		if ln == 0 {
-			fmt.Println(colored)
-		} else {
-			fmt.Println(addLineNum(colored, ln))
-		}
-	} else {
-		fmt.Print(addFileName(colored, kolorit.files[i], ln))
+func newKolorit() *kolorit {
+	kolorit := &kolorit{
+		options:    make(map[string]bool),
+		strOptions: make(map[string]string),
+		intOptions: make(map[string]int),
+		cliOptions: make(map[string]bool),
+		regexps:    make(map[string]string),
+		bgColors:   make(map[string]string),
+		bg:         make(map[string]int),
+		files:      make([]string, 0),
+		fileTypes:  make(map[string][]string),
+		selected:   make(map[string]*kolorit),
+		patterns:   builtinPatterns,
 	}
+	for k, v := range defaultFileTypes {
+		kolorit.fileTypes[k] = v
+	}
+	return kolorit
 }
 
-func (kolorit *kolorit) seekDir(files *[]string, dirName string) {
-	if isDebug {
-		log.Println("### seekDir")
-		log.Println("File Name:" + kolorit.fileName)
-		log.Println("Dir Name:" + dirName)
-	}
-	fileInfo, ioerr := ioutil.ReadDir(dirName)
-	if ioerr != nil {
-		log.Println(ioerr.Error() + " :error on reading dir: " + dirName)
-	} else {
-		for i := 0; i < len(fileInfo); i++ {
-			fullName := filepath.Join(dirName, fileInfo[i].Name())
-			if fileInfo[i].IsDir() == false {
-				if !kolorit.isIgnoreFile(fileInfo[i].Name()) && (kolorit.fileName == "" || kolorit.checkFileName(fullName)) {
-					if isDebug {
-						log.Println("File Full Name: " + fullName)
-					}
-					*files = append(*files, fullName)
-				}
-			} else if kolorit.isRecursive && !kolorit.isIgnoreDirs(fileInfo[i].Name()) {
-				if isDebug {
-					log.Println("Seek Dir: " + filepath.Join(dirName, fileInfo[i].Name()))
-				}
-				kolorit.seekDir(files, filepath.Join(dirName, fileInfo[i].Name()))
-			}
-		}
+func (kolorit *kolorit) printColored(colored string, name string, ln int) {
+	fmt.Println(kolorit.addPrefix(colored, name, ln))
+}
+
+// addPrefix adds file name and line number to colored content
+func (kolorit *kolorit) addPrefix(colored string, name string, ln int) string {
+	if kolorit.showFileName {
+		return addFileName(colored, name, ln)
+	} else if ln == 0 || kolorit.fromSTDIN {
+		return colored
 	}
+	return addLineNum(colored, ln)
 }
 
 func (kolorit *kolorit) isIgnoreFile(file string) (ignore bool) {
@@ -395,27 +355,22 @@ func (kolorit *kolorit) isIgnoreFile(file string) (ignore bool) {
 			ignore = true
 		}
 	}
+	if ignore {
+		return
+	}
+	if kolorit.matchFileTypes(file, kolorit.excludeTypes) {
+		return true
+	}
+	if kolorit.options["archives"] && isReadableArchive(file) {
+		return false
+	}
+	if len(kolorit.selectTypes) > 0 {
+		return !kolorit.matchFileTypes(file, kolorit.selectTypes)
+	}
 	if !kolorit.options["ext"] {
-		// https://en.wikipedia.org/wiki/Image_file_formats#Raster_formats
-		ignore, _ = regexp.MatchString("(?i)\\.(jpe?g|png|gif|bmp|raw2?|tiff?|p[pgbn]|hei[fc]|bpg|webp|ico|psd|xcf|svg|swf|pdf|ai|cgm|gbr)", file)
-		if ignore {
-			return
-		}
-		// https://en.wikipedia.org/wiki/Video_file_format
-		ignore, _ = regexp.MatchString("(?i)\\.(webm|flv|vob|ogv|ogg|drc|gifv|mng|avi|mov|qt|wmv|yuv|rm|rmvb|asf|amv|mp4|m4[pv]|mp[g2v]|mpeg?|svi|3g[2p]|mxf|roq|nsv|f[l4]v|f4[pab])$", file)
-		if ignore {
-			return
-		}
-		// https://en.wikipedia.org/wiki/Audio_file_format
-		ignore, _ = regexp.MatchString("(?i)\\.(3gp|aa[cx]?|act|aiff|amr|ape|au|awb|dct|dss|dvf|flac?|gsm|iklax|m4[abp]|mmf|mp[3c]|msv|m?og[ga]|opus|r[am]|raw|sln|tta|vox|wav|wma|wv|webm)$", file)
-		if ignore {
-			return
-		}
-
-		//https://en.wikipedia.org/wiki/List_of_archive_formats
-		ignore, _ = regexp.MatchString("(?i)\\.(ar?|cpio|shar|lbr|iso|mar|tar|bz2|gz|lz(?:ma|o)?|rz|sfark|sz|xz|z|s?7z|ace|afa|alz|apk|arc|arj|b[1ah]|ca[br]|cfs|cpt|dar|dd|dgc|dmg|ear|gca|ha|hki|ice|jar|kgb|lz[ha]|pak|partimg|pag|pea|pim|pit|qda|rar|rk|sda|sea|sen|sfx|shk|si|sitx|sqx|uc\\d?|uca|uha|war|wim|xar|xp3|yz1|zipx?|zoo|zpaq|zz)$", file)
-		if ignore {
-			return
+		ignore = kolorit.matchFileTypes(file, ignoredFileTypes)
+		if !ignore && kolorit.options["nz"] {
+			ignore = kolorit.matchFileTypes(file, []string{"compressed"})
 		}
 	}
 	return
@@ -457,25 +412,25 @@ func addFileName(content string, fn string, ln int) string {
 	} else {
 		prefix = a.Str(fn).Magenta().Str(":").Cyan().Str(strconv.Itoa(ln)).Yellow().Str(":").Cyan().String()
 	}
-	return resetRegexp.ReplaceAllString(content, prefix+"$1") + "\n"
+	return withPrefix(content, prefix)
 }
 
 func addLineNum(content string, ln int) string {
 	a := ansistrings.NewANSIStrings()
 	prefix := a.Str(strconv.Itoa(ln)).Yellow().Str(":").Cyan().String()
-	return resetRegexp.ReplaceAllString(content, prefix+"$1")
+	return withPrefix(content, prefix)
 }
 
 func (kolorit *kolorit) checkFileName(targetFile string) bool {
-	kolorit.pattern = kolorit.fileName
-	kolorit.pattern = strings.Replace(kolorit.pattern, ".", "\\.", -1)
-	kolorit.pattern = strings.Replace(kolorit.pattern, "*", ".*", -1)
-	matched, err := regexp.MatchString("(^|/)"+kolorit.pattern+"$", targetFile)
+	pattern := kolorit.fileName
+	pattern = strings.Replace(pattern, ".", "\\.", -1)
+	pattern = strings.Replace(pattern, "*", ".*", -1)
+	matched, err := regexp.MatchString("(^|/)"+pattern+"$", targetFile)
 	if isDebug {
 		log.Println("### checkFileName")
 		log.Println("Target File: " + targetFile)
 		log.Println("File Name: " + kolorit.fileName)
-		log.Println("Pattern: " + kolorit.pattern)
+		log.Println("Pattern: " + pattern)
 		log.Printf("Matched: %t\n", matched)
 	}
 	if err == nil && matched {
@@ -485,14 +440,9 @@ func (kolorit *kolorit) checkFileName(targetFile string) bool {
 }
 
 func (kolorit *kolorit) parseOptions() {
-	var err error
-	replace := make([]string, 0)
-	regexpFlg := ""
-	regexpFlgs := make(map[byte]bool)
-	colorOptions := make([]string, 0)
-	colorHelp := make([]string, 0)
 	boolParsedOpt := make(map[string]*bool)
 	strParsedOpt := make(map[string]*string)
+	intParsedOpt := make(map[string]*int)
 	regexps := make(map[string]*string)
 	bgOptions := make(map[string]*string)
 
@@ -506,10 +456,13 @@ func (kolorit *kolorit) parseOptions() {
 			boolParsedOpt[v.k] = flag.Bool(v.k, v.boolDef, v.help)
 		} else if v.isString {
 			strParsedOpt[v.k] = flag.String(v.k, v.strDef, v.help)
+		} else if v.isInt {
+			intParsedOpt[v.k] = flag.Int(v.k, v.intDef, v.help)
 		}
 	}
 
-	flag.Parse()
+	parseEnvArgs(flag.CommandLine)
+	dashed := parseArgs(flag.CommandLine, os.Args[1:])
 
 	// parse options
 	for k, v := range boolParsedOpt {
@@ -522,21 +475,48 @@ func (kolorit *kolorit) parseOptions() {
 			kolorit.strOptions[k] = *v
 		}
 	}
+	for k, v := range intParsedOpt {
+		if v != nil {
+			kolorit.intOptions[k] = *v
+		}
+	}
+	for k := range colorMap {
+		kolorit.regexps[k] = *regexps[k]
+		kolorit.bgColors["b"+k] = *bgOptions["b"+k]
+	}
 
-	isDebug = kolorit.options["d"]
+	flag.Visit(func(f *flag.Flag) {
+		kolorit.cliOptions[f.Name] = true
+	})
 
-	kolorit.isRecursive = kolorit.options["R"]
+	isDebug = kolorit.options["d"]
 
 	// print usage and exit
 	if kolorit.options["help"] || kolorit.options["h"] {
 		usage()
 	}
 
+	if name := kolorit.strOptions["save-profile"]; name != "" {
+		kolorit.saveProfile(name)
+	}
+
 	// options from config file
-	kolorit.parseConfig(kolorit.strOptions["conf"], kolorit.strOptions["use"], colorMap, &regexps)
+	kolorit.base = kolorit.copyOptions()
+	kolorit.parseConfig(kolorit.strOptions["conf"], kolorit.strOptions["use"])
+	kolorit.isRecursive = kolorit.options["R"]
+	kolorit.setRenderer()
 
-	kolorit.erasePattern = kolorit.strOptions["e"]
-	kolorit.asSingle = kolorit.options["s"]
+	if kolorit.options["pattern-list"] {
+		printPatterns(kolorit.patterns)
+		os.Exit(0)
+	}
+	if kolorit.options["type-list"] {
+		kolorit.printFileTypes()
+		os.Exit(0)
+	}
+	kolorit.selectTypes = kolorit.parseTypeNames(kolorit.strOptions["t"])
+	kolorit.excludeTypes = kolorit.parseTypeNames(kolorit.strOptions["T"])
+	kolorit.parseWalkOptions()
 
 	// rest args after options are regareded as files
 	for n := 0; n < flag.NArg(); n++ {
@@ -545,19 +525,39 @@ func (kolorit *kolorit) parseOptions() {
 		}
 		kolorit.files = append(kolorit.files, flag.Arg(n))
 	}
+	if len(dashed) > 0 && (kolorit.options["run"] || !isFile(dashed[0], kolorit.isRecursive)) {
+		kolorit.setWrapped(dashed)
+	} else if kolorit.options["run"] {
+		errMessage("command is not given after -- with -run")
+	}
 
-	if kolorit.strOptions["f"] != "" && kolorit.strOptions["f"] != "-" {
+	if from := kolorit.strOptions["files-from"]; from != "" {
+		for _, f := range kolorit.files {
+			if f == stdinName && from == stdinName {
+				errMessage("cannot read STDIN as both -files-from and file")
+			}
+		}
+		kolorit.readFilesFrom(from)
+		if len(kolorit.files) == 0 {
+			errMessage("no file names are given from: " + from)
+		}
+	}
+
+	if kolorit.strOptions["f"] != "" && kolorit.strOptions["f"] != stdinName {
 		kolorit.fileName = kolorit.strOptions["f"]
-	} else if len(kolorit.files) == 0 && !kolorit.isRecursive {
+	} else if len(kolorit.files) == 0 && !kolorit.isRecursive && kolorit.wrapped == nil {
 		kolorit.fromSTDIN = true
 	}
 
 	// collect target files
-	if !kolorit.fromSTDIN {
+	if !kolorit.fromSTDIN && kolorit.wrapped == nil {
 		if len(kolorit.files) == 0 && kolorit.fileName != "" {
 			kolorit.seekDir(&kolorit.files, ".")
 		} else if kolorit.isRecursive {
 			for _, f := range kolorit.files {
+				if f == stdinName {
+					continue
+				}
 				fi, err := os.Stat(f)
 				errCheck(err, "error on stat file: "+f)
 				if fi.IsDir() {
@@ -570,6 +570,28 @@ func (kolorit *kolorit) parseOptions() {
 		}
 	}
 
+	kolorit.prepare()
+	if kolorit.numOfRegexps == 0 && len(kolorit.autoProfiles) == 0 {
+		colorHelp := make([]string, 0)
+		for _, k := range colorNames {
+			colorHelp = append(colorHelp, "-"+k)
+		}
+		errMessage("any of " + strings.Join(colorHelp, ", ") + " AND -R, -f or file names as rest of args is required.\n")
+	}
+}
+
+// prepare builds regexps and options for coloring from parsed options
+func (kolorit *kolorit) prepare() {
+	var err error
+	replace := make([]string, 0)
+	regexpFlg := ""
+	regexpFlgs := make(map[byte]bool)
+
+	kolorit.erasePattern = kolorit.expanded("-e", kolorit.strOptions["e"])
+	kolorit.asSingle = kolorit.options["s"]
+	kolorit.numOfRegexps = 0
+	kolorit.bg = make(map[string]int)
+
 	// build regexp flags
 	for _, k := range []byte{'s', 'i'} {
 		regexpFlgs[k] = kolorit.options[string(k)]
@@ -584,192 +606,53 @@ func (kolorit *kolorit) parseOptions() {
 	regexpFlg = "(?" + regexpFlg + ")"
 
 	// build regexps
-	for k := range colorMap {
-		if *regexps[k] != "" {
-			replace = append(replace, fmt.Sprintf("(?P<%s>%s)", colorMap[k], *regexps[k]))
-			colorOptions = append(colorOptions, string(k))
+	for _, k := range colorNames {
+		if kolorit.regexps[k] != "" {
+			replace = append(replace, fmt.Sprintf("(?P<%s>%s)", colorMap[k], kolorit.expanded("-"+k, kolorit.regexps[k])))
 			kolorit.numOfRegexps++
 		}
-		colorHelp = append(colorHelp, "-"+string(k))
-		v, ok := bgOptions["b"+k]
-		if ok && *v != "" {
-			kolorit.bg[k], err = ansistrings.ColorNumFromName(*v)
+		if v := kolorit.bgColors["b"+k]; v != "" {
+			kolorit.bg[k], err = ansistrings.ColorNumFromName(v)
 			if err != nil {
-				errCheck(err, "unknown color name: "+*v)
+				errCheck(err, "unknown color name: "+v)
 			}
 		}
 	}
 
-	if len(replace) == 0 {
-		errMessage("any of " + strings.Join(colorHelp, ", ") + " AND -R, -f or file names as rest of args is required.\n")
-	}
-
 	// assemble regexps
 	kolorit.pattern = regexpFlg + strings.Join(replace, "|")
 	if isDebug {
 		log.Println("regexp: " + kolorit.pattern)
 	}
+	kolorit.re, err = regexp.Compile(kolorit.pattern)
+	errCheck(err, "wrong regexp: "+kolorit.pattern)
+	kolorit.reErase, err = regexp.Compile(kolorit.erasePattern)
+	errCheck(err, "wrong regexp: "+kolorit.erasePattern)
 }
 
-func (kolorit *kolorit) parseConfig(configFile string, use string, colorMap map[string]string, regexps *map[string]*string) {
-	if use == "default" {
-		errMessage("cannot pass 'default' as 'use' argument")
-	}
-
-	_, err := os.Stat(configFile)
-	if err != nil {
-		errCheck(err, "cannot find/read config file:"+configFile)
-	}
-
-	config, err := toml.LoadFile(configFile)
+func (kolorit *kolorit) coloringText(re *regexp.Regexp, reErase *regexp.Regexp, lines string) (string, int, error) {
+	lines, spans, matchedKind, err := kolorit.matchText(re, reErase, lines)
 	if err != nil {
-		if use != "" {
-			errMessage("cannot parse config file: " + configFile)
-		} else {
-			return
-		}
-	}
-	opt := config.Get(use)
-	switch opt.(type) {
-	case nil:
-		errCheck(nil, "'"+use+"' is not defined in "+configFile)
-	}
-
-	defaultOpt := config.Get("default")
-	switch defaultOpt.(type) {
-	case nil:
-		defaultOpt = &toml.TomlTree{}
-	}
-
-	optionKeys := make([]string, 0)
-	for k := range colorMap {
-		optionKeys = append(optionKeys, k)
-	}
-	optionKeys = append(optionKeys, "e")
-
-	for _, k := range optionKeys {
-		isRegexp := opt.(*toml.TomlTree).Get(string(k))
-		switch isRegexp.(type) {
-		case nil:
-			isRegexp = defaultOpt.(*toml.TomlTree).Get(string(k))
-		}
-		switch isRegexp.(type) {
-		case nil:
-			continue
-		default:
-			regexpStr := isRegexp.(string)
-			if k == "e" {
-				kolorit.strOptions[string(k)] = regexpStr
-			} else {
-				if *(*regexps)[k] == "" && regexpStr != "" {
-					(*regexps)[k] = &regexpStr
-				}
-
-			}
-		}
-	}
-	boolOpts := []string{"B", "m", "i", "s", "I", "grep", "ngrep", "nI", "nB"}
-	for _, k := range boolOpts {
-		boolOpt := opt.(*toml.TomlTree).Get(k)
-		switch boolOpt.(type) {
-		case nil:
-			boolOpt = defaultOpt.(*toml.TomlTree).Get(k)
-		}
-		switch boolOpt.(type) {
-		case nil:
-			continue
-		default:
-			kolorit.options[k] = boolOpt.(bool)
-		}
-	}
-	nArry := []string{"grep", "I", "B"}
-	for _, k := range nArry {
-		if kolorit.options["n"+k] {
-			kolorit.options[k] = false
-		}
+		return "", 0, err
 	}
+	return kolorit.renderANSI(lines, spans), matchedKind, nil
 }
 
-func (kolorit *kolorit) coloringText(re *regexp.Regexp, reErase *regexp.Regexp, lines string) (string, int, error) {
+// matchText returns text which matched string with reErase is erased, spans of it matched with re and number of kinds of matched colors
+func (kolorit *kolorit) matchText(re *regexp.Regexp, reErase *regexp.Regexp, lines string) (string, []span, int, error) {
 	for i := 0; i < len(lines); i++ {
 		if utf8.ValidString(lines) == false && !kolorit.options["force"] {
-			return "", 0, errors.New("binary string or not utf-8 character is given")
+			return "", nil, 0, errors.New("binary string or not utf-8 character is given")
 		}
 	}
 
-	lines = reErase.ReplaceAllString(lines, "")
-	colorFunc := make(map[string]func(s ansistrings.ANSIString) string)
-	for _, colorName := range colorNames {
-		name := colorMap[colorName]
-		n, _ := ansistrings.ColorNumFromName(name)
-		colorFunc[name] = func(s ansistrings.ANSIString) string {
-			s.Color(n)
-			return s.String()
-		}
+	if kolorit.options["strip-ansi"] {
+		lines = stripANSI(lines)
+	} else if strings.Contains(lines, "\033") {
+		lines, spans, matchedKind := kolorit.matchEscaped(re, reErase, lines)
+		return lines, spans, matchedKind, nil
 	}
-
-	machedKind := 0
-	machedName := make(map[string]int)
-	lines = re.ReplaceAllStringFunc(lines, func(s string) string {
-		result := make(map[string][]int)
-		match := re.FindAllStringSubmatchIndex(s, -1)
-		lastName := ""
-		for i, name := range re.SubexpNames() {
-			if i < 1 || match[0][i*2] == -1 {
-				continue
-			}
-			if lastName != "" && name == "" {
-				result[lastName] = append(result[lastName], match[0][i*2], match[0][i*2+1])
-			} else {
-				result[name] = append(result[name], match[0][i*2], match[0][i*2+1])
-				lastName = name
-				machedName[lastName]++
-				if machedName[lastName] == 1 {
-					machedKind++
-				}
-			}
-		}
-
-		for _, colorName := range colorNames {
-			k := colorMap[colorName]
-			newStr := ""
-			if len(result[k]) > 2 { // if parenthese exists in regexp, ignore first match which matches whole string
-				result[k] = result[k][2:]
-			}
-			for i := len(result[k]) - 1; i >= 0; i -= 2 {
-				if result[k][i] > 0 {
-					var matchedIndex []int
-					matchedIndex = append(matchedIndex, result[k][i-1], result[k][i])
-					var color ansistrings.ANSIString
-					if kolorit.options["B"] {
-						color.Bold()
-					}
-					if kolorit.options["I"] {
-						color.Inverted()
-					}
-					if kolorit.options["U"] {
-						color.UnderLine()
-					}
-					v, ok := kolorit.bg[colorName]
-					if ok {
-						color.BgColor(v)
-					}
-
-					if matchedIndex[1] > 0 {
-						color.Str = s[matchedIndex[0]:matchedIndex[1]]
-					}
-					if matchedIndex[0] > 0 {
-						newStr = s[0:matchedIndex[0]]
-					}
-					newStr += colorFunc[k](color)
-					if matchedIndex[1] > 0 && matchedIndex[1] < len(s) {
-						newStr += s[matchedIndex[1]:len(s)]
-					}
-					s = newStr
-				}
-			}
-		}
-		return s
-	})
-	return string(lines), machedKind, nil
+	lines = reErase.ReplaceAllString(lines, "")
+	spans, matchedKind := findSpans(re, lines)
+	return lines, spans, matchedKind, nil
 }
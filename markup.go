@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// markupRenderer writes each line in markup of other programs
+type markupRenderer struct {
+	markup func(seg segment) string
+}
+
+func (m markupRenderer) begin(w io.Writer) {}
+
+func (m markupRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	var b strings.Builder
+	for _, seg := range kolorit.segmentsOf(name, ln, text, spans) {
+		b.WriteString(m.markup(seg))
+	}
+	fmt.Fprintln(w, b.String())
+}
+
+func (m markupRenderer) end(w io.Writer) {}
+
+// names of colors in tmux
+var tmuxColors = map[string]string{
+	"red": "red", "green": "green", "blue": "blue", "yellow": "yellow",
+	"purple": "magenta", "cyan": "cyan", "black": "black", "white": "white",
+	"light_red": "brightred", "light_green": "brightgreen", "light_blue": "brightblue", "light_yellow": "brightyellow",
+	"light_purple": "brightmagenta", "light_cyan": "brightcyan", "dark_gray": "brightblack", "light gray": "white",
+}
+
+// tmuxMarkup returns segment in format of tmux like "#[fg=red,bold]text#[default]"
+func tmuxMarkup(seg segment) string {
+	text := strings.Replace(seg.text, "#", "##", -1)
+	attrs := make([]string, 0)
+	switch {
+	case seg.color != "":
+		attrs = append(attrs, "fg="+tmuxColors[seg.color])
+	case seg.style != nil:
+		attrs = append(attrs, "fg="+tmuxColors[colorMap[seg.style.color]])
+		if seg.style.bg != "" {
+			attrs = append(attrs, "bg="+tmuxColors[colorMap[seg.style.bg]])
+		}
+		for _, a := range []struct {
+			on   bool
+			attr string
+		}{{seg.style.bold, "bold"}, {seg.style.underline, "underscore"}, {seg.style.inverse, "reverse"}} {
+			if a.on {
+				attrs = append(attrs, a.attr)
+			}
+		}
+	default:
+		return text
+	}
+	return "#[" + strings.Join(attrs, ",") + "]" + text + "#[default]"
+}
+
+// pangoMarkup returns segment in Pango markup like <span foreground="#cd3131">text</span>
+func pangoMarkup(seg segment) string {
+	text := html.EscapeString(seg.text)
+	switch {
+	case seg.color != "":
+		return fmt.Sprintf(`<span foreground="%s">%s</span>`, rgbColors[seg.color], text)
+	case seg.style != nil:
+		fg, bg := rgbColors[colorMap[seg.style.color]], ""
+		if seg.style.bg != "" {
+			bg = rgbColors[colorMap[seg.style.bg]]
+		}
+		if seg.style.inverse {
+			fg, bg = rgbBackground, fg
+		}
+		attrs := ` foreground="` + fg + `"`
+		if bg != "" {
+			attrs += ` background="` + bg + `"`
+		}
+		if seg.style.bold {
+			attrs += ` weight="bold"`
+		}
+		if seg.style.underline {
+			attrs += ` underline="single"`
+		}
+		return "<span" + attrs + ">" + text + "</span>"
+	}
+	return text
+}
+
+// numbers of colors of mIRC
+var ircColors = map[string]string{
+	"red": "05", "green": "03", "blue": "02", "yellow": "07",
+	"purple": "06", "cyan": "10", "black": "01", "white": "00",
+	"light_red": "04", "light_green": "09", "light_blue": "12", "light_yellow": "08",
+	"light_purple": "13", "light_cyan": "11", "dark_gray": "14", "light gray": "15",
+}
+
+// control codes of mIRC formatting
+const (
+	ircColor     = "\x03"
+	ircBold      = "\x02"
+	ircUnderline = "\x1f"
+	ircReverse   = "\x16"
+	ircReset     = "\x0f"
+)
+
+// ircMarkup returns segment with control codes of mIRC formatting
+func ircMarkup(seg segment) string {
+	switch {
+	case seg.color != "":
+		return ircColor + ircColors[seg.color] + seg.text + ircReset
+	case seg.style != nil:
+		codes := ircColor + ircColors[colorMap[seg.style.color]]
+		if seg.style.bg != "" {
+			codes += "," + ircColors[colorMap[seg.style.bg]]
+		}
+		for _, c := range []struct {
+			on   bool
+			code string
+		}{{seg.style.bold, ircBold}, {seg.style.underline, ircUnderline}, {seg.style.inverse, ircReverse}} {
+			if c.on {
+				codes += c.code
+			}
+		}
+		return codes + seg.text + ircReset
+	}
+	return seg.text
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// builtinPatterns can be used as {{name}} in regexps without defining them in [patterns]
+var builtinPatterns = map[string]string{
+	"ipv4":     `\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`,
+	"ipv6":     `(?i:\b(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}\b|(?:\b[0-9a-f]{1,4})?(?::[0-9a-f]{1,4})*::(?:[0-9a-f]{1,4}(?::[0-9a-f]{1,4})*\b)?)`,
+	"mac":      `(?i:\b[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}\b)`,
+	"uuid":     `(?i:\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b)`,
+	"hex":      `\b0[xX][0-9a-fA-F]+\b`,
+	"number":   `[-+]?\b\d+(?:\.\d+)?\b`,
+	"date":     `\b\d{4}[/-]\d{2}[/-]\d{2}`,
+	"time":     `\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?`,
+	"timezone": `(?:Z|[+-]\d{2}:?\d{2})`,
+	"datetime": `{{date}}[T ]{{time}}{{timezone}}?`,
+	"duration": `\b\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))*\b`,
+	"email":    `\b[\w.%+-]+@[\w.-]+\.[A-Za-z]{2,}\b`,
+	"url":      `\b[a-z][a-z0-9+.-]*://[^\s"'<>]+`,
+	"path":     `(?:~|\.{1,2})?/[\w.@%+-]+(?:/[\w.@%+-]+)*/?`,
+	"loglevel": `(?i:\b(?:trace|debug|info|notice|warn(?:ing)?|error|err|crit(?:ical)?|alert|emerg(?:ency)?|fatal|panic)\b)`,
+}
+
+var patternRefRegexp = regexp.MustCompile(`\{\{([A-Za-z0-9_-]+)\}\}`)
+
+// patterns returns built-in patterns merged with [patterns] sections of config files
+func (c *config) patterns() (map[string]string, error) {
+	patterns := make(map[string]string)
+	for k, v := range builtinPatterns {
+		patterns[k] = v
+	}
+	section := c.section("patterns")
+	if section == nil {
+		return patterns, nil
+	}
+	for _, k := range section.keys() {
+		s, ok := section.values[k].(string)
+		if !ok {
+			return nil, errors.New("'" + k + "' in [patterns] must be a string")
+		}
+		patterns[k] = s
+	}
+	return patterns, nil
+}
+
+// expandPatterns replaces {{name}} in regexp with patterns recursively
+func expandPatterns(s string, patterns map[string]string) (string, error) {
+	return expandPattern(s, patterns, nil)
+}
+
+func expandPattern(s string, patterns map[string]string, chain []string) (string, error) {
+	var err error
+	expanded := patternRefRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		name := patternRefRegexp.FindStringSubmatch(ref)[1]
+		if err != nil {
+			return ref
+		}
+		for _, n := range chain {
+			if n == name {
+				err = errors.New("circular pattern: " + strings.Join(append(chain, name), " -> "))
+				return ref
+			}
+		}
+		p, ok := patterns[name]
+		if !ok {
+			err = errors.New("unknown pattern: {{" + name + "}}")
+			return ref
+		}
+		if p, err = expandPattern(p, patterns, append(chain[:len(chain):len(chain)], name)); err != nil {
+			return ref
+		}
+		return "(?:" + p + ")"
+	})
+	return expanded, err
+}
+
+// expanded returns regexp which patterns are expanded. it exits when expanding is failed.
+func (kolorit *kolorit) expanded(where string, s string) string {
+	expanded, err := expandPatterns(s, kolorit.patterns)
+	if err != nil {
+		errMessage(err.Error() + " in " + where)
+	}
+	return expanded
+}
+
+func printPatterns(patterns map[string]string) {
+	names := make([]string, 0, len(patterns))
+	for k := range patterns {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Printf("%-10s %s\n", k, patterns[k])
+	}
+}
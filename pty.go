@@ -0,0 +1,98 @@
+//go:build !windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// startCommand starts command with pseudo terminals for its stdout and stderr when they are terminals,
+// so that the command behaves as it is run in terminal. stdin shares pseudo terminal with stdout when it is a terminal.
+// size of terminal is passed to pseudo terminals when it is changed. returned function restores terminal.
+func (kolorit *kolorit) startCommand(cmd *exec.Cmd) (io.Reader, io.Reader, func(), error) {
+	isTerminal := func(f *os.File) bool { return kolorit.options["pty"] && term.IsTerminal(int(f.Fd())) }
+	if !isTerminal(os.Stdout) && !isTerminal(os.Stderr) {
+		return startWithPipes(cmd)
+	}
+
+	var stdout, stderr io.Reader
+	var stdin *os.File
+	ttys := make([]*os.File, 0, 2)
+	// pseudo terminals and terminals which sizes are passed to them
+	sizes := make(map[*os.File]*os.File)
+	ctty := -1
+	cmd.Stdin = os.Stdin
+	if isTerminal(os.Stdout) {
+		ptmx, tty, err := pty.Open()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ttys, sizes[ptmx] = append(ttys, tty), os.Stdout
+		cmd.Stdout, stdout, ctty = tty, ptmx, 1
+		if isTerminal(os.Stdin) {
+			cmd.Stdin, stdin, ctty = tty, ptmx, 0
+		}
+	} else {
+		r, err := cmd.StdoutPipe()
+		errCheck(err, "error on creating pipe of stdout")
+		stdout = r
+	}
+	if isTerminal(os.Stderr) {
+		ptmx, tty, err := pty.Open()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ttys, sizes[ptmx] = append(ttys, tty), os.Stderr
+		cmd.Stderr, stderr = tty, ptmx
+		if ctty < 0 {
+			ctty = 2
+		}
+	} else {
+		r, err := cmd.StderrPipe()
+		errCheck(err, "error on creating pipe of stderr")
+		stderr = r
+	}
+
+	resize := func() {
+		for ptmx, terminal := range sizes {
+			pty.InheritSize(terminal, ptmx)
+		}
+	}
+	resize()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: ctty}
+	err := cmd.Start()
+	for _, tty := range ttys {
+		tty.Close()
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	go func() {
+		for range winch {
+			resize()
+		}
+	}()
+	restore := func() { signal.Stop(winch) }
+	if stdin != nil {
+		// keys are passed to the command as they are, and pseudo terminal handles them(e.g. Ctrl-C)
+		fd := int(os.Stdin.Fd())
+		if state, err := term.MakeRaw(fd); err == nil {
+			restore = func() {
+				signal.Stop(winch)
+				term.Restore(fd, state)
+			}
+		}
+		go io.Copy(stdin, os.Stdin)
+	}
+	return stdout, stderr, restore, nil
+}
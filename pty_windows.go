@@ -0,0 +1,11 @@
+package main
+
+import (
+	"io"
+	"os/exec"
+)
+
+// startCommand starts command with pipes because pseudo terminal is not supported on windows
+func (kolorit *kolorit) startCommand(cmd *exec.Cmd) (io.Reader, io.Reader, func(), error) {
+	return startWithPipes(cmd)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// renderer writes text colored with spans in a format
+type renderer interface {
+	// begin is called before reading inputs
+	begin(w io.Writer)
+	// write writes a line of input, or whole content of input(ln is 0) with -s
+	write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span)
+	// end is called after reading all inputs
+	end(w io.Writer)
+}
+
+// renderers for -format
+var renderers = map[string]func() renderer{
+	"ansi":          func() renderer { return ansiRenderer{} },
+	"html":          func() renderer { return &htmlRenderer{} },
+	"html-fragment": func() renderer { return &htmlRenderer{fragment: true} },
+	"json":          func() renderer { return jsonRenderer{} },
+	"sarif":         func() renderer { return &sarifRenderer{} },
+	"quickfix":      func() renderer { return quickfixRenderer{} },
+	"svg":           func() renderer { return &svgRenderer{} },
+	"asciicast":     func() renderer { return &asciicastRenderer{} },
+	"tmux":          func() renderer { return markupRenderer{markup: tmuxMarkup} },
+	"pango":         func() renderer { return markupRenderer{markup: pangoMarkup} },
+	"irc":           func() renderer { return markupRenderer{markup: ircMarkup} },
+}
+
+// formatNames returns names of formats for help
+func formatNames() string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// setRenderer sets renderer of -format
+func (kolorit *kolorit) setRenderer() {
+	newRenderer, ok := renderers[kolorit.strOptions["format"]]
+	if !ok {
+		errMessage("unknown format: " + kolorit.strOptions["format"] + ". format must be one of " + formatNames())
+	}
+	kolorit.renderer = newRenderer()
+}
+
+// output writes colored text with renderer
+func (kolorit *kolorit) output(text string, spans []span, name string, ln int) {
+	switch kolorit.renderer.(type) {
+	case ansiRenderer, *asciicastRenderer:
+	default:
+		// escape sequences in input are written only in formats of terminal
+		text, spans = plainOf(text, spans)
+	}
+	kolorit.renderer.write(os.Stdout, kolorit, name, ln, text, spans)
+}
+
+// ansiRenderer colors text with ANSI escape sequences
+type ansiRenderer struct{}
+
+func (ansiRenderer) begin(w io.Writer) {}
+
+func (ansiRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	kolorit.printColored(kolorit.renderANSI(text, spans), name, ln)
+}
+
+func (ansiRenderer) end(w io.Writer) {}
+
+// colors of color names used by renderers other than ansi
+var rgbColors = map[string]string{
+	"red": "#cd3131", "green": "#0dbc79", "blue": "#2472c8", "yellow": "#e5e510",
+	"purple": "#bc3fbc", "cyan": "#11a8cd", "black": "#000000", "white": "#e5e5e5",
+	"light_red": "#f14c4c", "light_green": "#23d18b", "light_blue": "#3b8eea", "light_yellow": "#f5f543",
+	"light_purple": "#d670d6", "light_cyan": "#29b8db", "dark_gray": "#666666", "light gray": "#bbbbbb",
+}
+
+// colors of background and text which is not colored
+const (
+	rgbBackground = "#1e1e1e"
+	rgbForeground = "#d4d4d4"
+)
+
+// style is how span of color is rendered. colors are short names, and bg is "" when it is not given.
+type style struct {
+	color     string
+	bg        string
+	bold      bool
+	underline bool
+	inverse   bool
+}
+
+func (kolorit *kolorit) styleOf(color string) style {
+	s := style{color: color, bold: kolorit.options["B"], underline: kolorit.options["U"], inverse: kolorit.options["I"]}
+	if bg, ok := colorOfGroup[kolorit.bgColors["b"+color]]; ok {
+		s.bg = bg
+	}
+	return s
+}
+
+// prefixOf returns file name and line number shown before content. they are shown in the same way as ansi.
+func (kolorit *kolorit) prefixOf(name string, ln int) (string, int) {
+	switch {
+	case kolorit.showFileName:
+		return homeDirRegexp.ReplaceAllString(name, "~/"), ln
+	case ln == 0 || kolorit.fromSTDIN:
+		return "", 0
+	}
+	return "", ln
+}
+
+// segment is a part of line which has the same style. style is nil for text which is not matched,
+// and color is name of color of file name and line number.
+type segment struct {
+	text  string
+	style *style
+	color string
+}
+
+// segmentsOf returns file name, line number and text of a line as segments
+func (kolorit *kolorit) segmentsOf(name string, ln int, text string, spans []span) []segment {
+	line := make([]segment, 0)
+	if name, ln := kolorit.prefixOf(name, ln); name != "" || ln > 0 {
+		if name != "" {
+			line = append(line, segment{text: name, color: "purple"}, segment{text: ":", color: "cyan"})
+		}
+		if ln > 0 {
+			line = append(line, segment{text: strconv.Itoa(ln), color: "yellow"}, segment{text: ":", color: "cyan"})
+		}
+	}
+	last := 0
+	for _, s := range spans {
+		st := kolorit.styleOf(s.color)
+		if s.start > last {
+			line = append(line, segment{text: text[last:s.start]})
+		}
+		line = append(line, segment{text: text[s.start:s.end], style: &st})
+		last = s.end
+	}
+	if last < len(text) {
+		line = append(line, segment{text: text[last:]})
+	}
+	return line
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"unicode/utf8"
+)
+
+// severities of rules. the first one is default
+var severities = []string{"warning", "error", "note"}
+
+// rule is severity and message of regexp of color declared in 'rules' table of profile
+type rule struct {
+	severity string
+	message  string
+}
+
+// ruleOf returns rule of color. default severity is used when it is not declared.
+func (kolorit *kolorit) ruleOf(color string) rule {
+	r := kolorit.rules[color]
+	if r.severity == "" {
+		r.severity = severities[0]
+	}
+	return r
+}
+
+// parseRules parses 'rules' table which has tables of color names like [NAME.rules.r]
+func parseRules(v interface{}) (map[string]rule, error) {
+	table, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("must be a table")
+	}
+	colors := make([]string, 0, len(table))
+	for color := range table {
+		colors = append(colors, color)
+	}
+	sort.Strings(colors)
+	rules := make(map[string]rule)
+	for _, color := range colors {
+		if _, ok := colorMap[color]; !ok {
+			return nil, errors.New("has unknown color: " + color)
+		}
+		values, ok := table[color].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("must have tables of colors")
+		}
+		var r rule
+		for k, v := range values {
+			s, ok := v.(string)
+			switch {
+			case k != "severity" && k != "message":
+				return nil, errors.New("has unknown key in '" + color + "': " + k)
+			case !ok:
+				return nil, errors.New("has '" + k + "' in '" + color + "' which is not a string")
+			case k == "severity" && !isSeverity(s):
+				return nil, errors.New("has unknown severity in '" + color + "': " + s)
+			case k == "severity":
+				r.severity = s
+			default:
+				r.message = s
+			}
+		}
+		rules[color] = r
+	}
+	return rules, nil
+}
+
+func isSeverity(s string) bool {
+	for _, severity := range severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRuleTable(v interface{}) error {
+	_, err := parseRules(v)
+	return err
+}
+
+// position is line and column of offset in text. columns start from 1.
+type position struct {
+	line int
+	// column in bytes(e.g. for quickfix of vim)
+	byteColumn int
+	// column in characters(Unicode code points)
+	column int
+}
+
+// positionOf returns position of byte offset in text which starts at line ln
+func positionOf(text string, ln int, offset int) position {
+	start := 0
+	for i := 0; i < offset; i++ {
+		if text[i] == '\n' {
+			ln++
+			start = i + 1
+		}
+	}
+	return position{line: ln, byteColumn: offset - start + 1, column: utf8.RuneCountInString(text[start:offset]) + 1}
+}
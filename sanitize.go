@@ -0,0 +1,104 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// sequenceRegexp matches escape sequence at the beginning of text
+var sequenceRegexp = regexp.MustCompile("^" + escapeRegexp.String())
+
+// eraseInLineRegexp matches sequence which erases line from cursor(e.g. written by grep --color)
+var eraseInLineRegexp = regexp.MustCompile("^\033\\[[0-2]?K$")
+
+// sanitizes returns whether control sequences of input are neutralized.
+// input from STDIN and commands is neutralized by default, and input from files only with -sanitize.
+func (kolorit *kolorit) sanitizes(fromFile bool) bool {
+	return !kolorit.options["nsanitize"] && (kolorit.options["sanitize"] || !fromFile)
+}
+
+// sanitized returns text which control sequences are neutralized when input is sanitized.
+// colors and progress(erasing in line and carriage return) of commands are kept.
+func (kolorit *kolorit) sanitized(text string) string {
+	if !kolorit.sanitizing {
+		return text
+	}
+	fromCommand := kolorit.wrapped != nil
+	return sanitize(text, kolorit.options["allow-sgr"] || fromCommand, fromCommand)
+}
+
+// sanitizedName returns name of input(e.g. file name and member of archive) which is neutralized as content of it.
+// SGR sequences in it are not kept.
+func (kolorit *kolorit) sanitizedName(name string) string {
+	if !kolorit.sanitizing {
+		return name
+	}
+	return sanitize(name, false, false)
+}
+
+// sanitize escapes control characters and escape sequences in text visibly like "^[]0;title^G"(same as cat -v).
+// tab, newline and carriage return before newline are kept. SGR sequences are kept with allowSGR, or removed.
+// erasing in line and carriage return are kept with allowProgress, or erasing in line is removed.
+func sanitize(text string, allowSGR bool, allowProgress bool) string {
+	if strings.IndexFunc(text, func(r rune) bool { return isControl(r) || r == utf8.RuneError }) == -1 {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if m := sequenceRegexp.FindStringIndex(text[i:]); m != nil {
+			seq := text[i : i+m[1]]
+			switch {
+			case sgrRegexp.MatchString(seq):
+				if allowSGR {
+					b.WriteString(seq)
+				}
+			case eraseInLineRegexp.MatchString(seq):
+				if allowProgress {
+					b.WriteString(seq)
+				}
+			default:
+				b.WriteString(caretNotation(seq))
+			}
+			i += m[1]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\t' || r == '\n' || r == '\r' && (allowProgress || strings.HasPrefix(text[i+1:], "\n")):
+			b.WriteRune(r)
+		case r == utf8.RuneError && size == 1 && text[i] >= 0x80 && text[i] < 0xa0:
+			// C1 control of 8 bits which is given with -force
+			b.WriteString("M-" + caretNotation(string(text[i]-0x80)))
+		case r >= 0x80 && r < 0xa0:
+			b.WriteString("M-" + caretNotation(string(r-0x80)))
+		case isControl(r):
+			b.WriteString(caretNotation(string(r)))
+		default:
+			b.WriteString(text[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// isControl returns whether r is control character other than tab and newline
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\t' && r != '\n' || r == 0x7f || r >= 0x80 && r < 0xa0
+}
+
+// caretNotation returns s which control characters are written like "^[", "^G" and "^?"
+func caretNotation(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c < 0x20:
+			b.WriteString("^" + string(c+0x40))
+		case c == 0x7f:
+			b.WriteString("^?")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		allowSGR      bool
+		allowProgress bool
+		want          string
+	}{
+		{"plain text", "héllo\tworld", false, false, "héllo\tworld"},
+		{"title", "a\033]0;title\007b", false, false, "a^[]0;title^Gb"},
+		{"clipboard terminated by ST", "\033]52;c;ZXZpbA==\033\\", false, false, "^[]52;c;ZXZpbA==^[\\"},
+		{"cursor movement", "\033[2A\033[10;1H", false, false, "^[[2A^[[10;1H"},
+		{"SGR is removed", "\033[01;31merror\033[m", false, false, "error"},
+		{"SGR is kept with allowSGR", "\033[01;31merror\033[m", true, false, "\033[01;31merror\033[m"},
+		{"private sequence is not SGR", "\033[>4;2m", true, false, "^[[>4;2m"},
+		{"erasing in line is removed", "\033[01;31m\033[Kerror\033[m\033[K", true, false, "\033[01;31merror\033[m"},
+		{"control characters", "a\bb\x00c\x7f", false, false, "a^Hb^@c^?"},
+		{"CR before LF is kept", "a\r\nb\r\n", false, false, "a\r\nb\r\n"},
+		{"CR in line", "abc\rxyz", false, false, "abc^Mxyz"},
+		{"CR at the end", "abc\r", false, false, "abc^M"},
+		{"C1 control in UTF-8", "a\u009b2Jb\u009d0;t\u009c", false, false, "aM-^[2JbM-^]0;tM-^\\"},
+		{"C1 control of 8 bits", "a\x9b2J", false, false, "aM-^[2J"},
+		{"invalid UTF-8 is kept", "a\xffb", false, false, "a\xffb"},
+		{"lone escape", "a\033", false, false, "a^["},
+		{"progress is kept with allowProgress", "\033[32m 50%\033[K\r 100%\r\n", true, true, "\033[32m 50%\033[K\r 100%\r\n"},
+		{"title is escaped with allowProgress", "\033]0;title\007\033[2A", true, true, "^[]0;title^G^[[2A"},
+	}
+	for _, tt := range tests {
+		if got := sanitize(tt.text, tt.allowSGR, tt.allowProgress); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizes(t *testing.T) {
+	tests := []struct {
+		options  map[string]bool
+		fromFile bool
+		want     bool
+	}{
+		{map[string]bool{}, false, true},
+		{map[string]bool{}, true, false},
+		{map[string]bool{"sanitize": true}, true, true},
+		{map[string]bool{"nsanitize": true}, false, false},
+		{map[string]bool{"sanitize": true, "nsanitize": true}, true, false},
+	}
+	for _, tt := range tests {
+		k := &kolorit{options: tt.options}
+		if got := k.sanitizes(tt.fromFile); got != tt.want {
+			t.Errorf("sanitizes(%v) with %v: got %v, want %v", tt.fromFile, tt.options, got, tt.want)
+		}
+	}
+}
+
+// namesRenderer records names of inputs written
+type namesRenderer struct {
+	names []string
+}
+
+func (r *namesRenderer) begin(w io.Writer) {}
+
+func (r *namesRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	r.names = append(r.names, name)
+}
+
+func (r *namesRenderer) end(w io.Writer) {}
+
+func TestSanitizedName(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]bool
+		file    string
+		member  string
+		want    string
+	}{
+		{"file name", map[string]bool{"sanitize": true}, "x\033]0;pwned\007.log", "", "x^[]0;pwned^G.log"},
+		{"member of archive", map[string]bool{"sanitize": true, "nz": true}, "evil.zip", "x\033]0;pwned\007.log", "evil.zip" + archiveSep + "x^[]0;pwned^G.log"},
+		{"SGR in file name", map[string]bool{"sanitize": true, "allow-sgr": true}, "\033[31mx.log", "", "x.log"},
+		{"file name without -sanitize", map[string]bool{}, "x\033]0;t\007.log", "", "x\033]0;t\007.log"},
+	}
+	for _, tt := range tests {
+		k := newKolorit()
+		k.options = tt.options
+		k.prepare()
+		r := &namesRenderer{}
+		k.renderer = r
+		if tt.member != "" {
+			k.readMember(strings.NewReader("a\n"), tt.file, tt.member)
+		} else {
+			k.readInput(strings.NewReader("a\n"), tt.file)
+		}
+		if len(r.names) != 1 || r.names[0] != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, r.names, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+)
+
+// sarifRenderer writes matched spans as results of SARIF 2.1.0 after reading all inputs
+type sarifRenderer struct {
+	rules   map[string]rule
+	results []sarifResult
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     *sarifMessage     `json:"shortDescription,omitempty"`
+	DefaultConfiguration map[string]string `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region sarifRegion `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int          `json:"startLine"`
+	StartColumn int          `json:"startColumn"`
+	EndLine     int          `json:"endLine"`
+	EndColumn   int          `json:"endColumn"`
+	Snippet     sarifMessage `json:"snippet"`
+}
+
+func (s *sarifRenderer) begin(w io.Writer) {
+	s.rules = make(map[string]rule)
+	s.results = make([]sarifResult, 0)
+}
+
+func (s *sarifRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	for _, sp := range spans {
+		r := kolorit.ruleOf(sp.color)
+		if _, ok := s.rules[sp.color]; !ok {
+			s.rules[sp.color] = r
+		}
+		result := sarifResult{RuleID: sp.color, Level: r.severity, Message: sarifMessage{Text: r.message}}
+		if result.Message.Text == "" {
+			result.Message.Text = text[sp.start:sp.end]
+		}
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = (&url.URL{Path: filepath.ToSlash(name)}).String()
+		start, end := positionOf(text, lineOf(ln), sp.start), positionOf(text, lineOf(ln), sp.end)
+		location.PhysicalLocation.Region = sarifRegion{
+			StartLine: start.line, StartColumn: start.column, EndLine: end.line, EndColumn: end.column,
+			Snippet: sarifMessage{Text: text[sp.start:sp.end]},
+		}
+		result.Locations = []sarifLocation{location}
+		s.results = append(s.results, result)
+	}
+}
+
+func (s *sarifRenderer) end(w io.Writer) {
+	ids := make([]string, 0, len(s.rules))
+	for id := range s.rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	driver := sarifDriver{Name: "kolorit", InformationURI: "https://github.com/ktat/kolorit", Rules: make([]sarifRule, 0, len(ids))}
+	for _, id := range ids {
+		r := s.rules[id]
+		sr := sarifRule{ID: id, Name: colorMap[id], DefaultConfiguration: map[string]string{"level": r.severity}}
+		if r.message != "" {
+			sr.ShortDescription = &sarifMessage{Text: r.message}
+		}
+		driver.Rules = append(driver.Rules, sr)
+	}
+	report := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, ColumnKind: "unicodeCodePoints", Results: s.results}},
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	errCheck(err, "error on writing SARIF")
+	fmt.Fprintln(w, string(b))
+}
+
+// lineOf returns line number of the first line of text. whole content read with -s starts at line 1.
+func lineOf(ln int) int {
+	if ln == 0 {
+		return 1
+	}
+	return ln
+}
+
+// quickfixRenderer writes matched spans as "file:line:col: rule: text" for quickfix of vim.
+// col is counted in bytes from 1 as %c of errorformat, and text is message of rule when it is declared, or matched text.
+type quickfixRenderer struct{}
+
+func (quickfixRenderer) begin(w io.Writer) {}
+
+func (quickfixRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	for _, s := range spans {
+		p := positionOf(text, lineOf(ln), s.start)
+		r := kolorit.ruleOf(s.color)
+		message := r.message
+		if message == "" {
+			message = text[s.start:s.end]
+		}
+		fmt.Fprintf(w, "%s:%d:%d: %s: %s\n", name, p.line, p.byteColumn, s.color, message)
+	}
+}
+
+func (quickfixRenderer) end(w io.Writer) {}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPositionOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		offset int
+		want   position
+	}{
+		{"ASCII", "an error", 3, position{line: 7, byteColumn: 4, column: 4}},
+		{"multibyte prefix", "日本 error", 7, position{line: 7, byteColumn: 8, column: 4}},
+		{"next line", "日本\n語 error", 11, position{line: 8, byteColumn: 5, column: 3}},
+	}
+	for _, tt := range tests {
+		if got := positionOf(tt.text, 7, tt.offset); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuickfixRenderer(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		spans []span
+		want  string
+	}{
+		{"ASCII", "an error", []span{{start: 3, end: 8, color: "r"}}, "x.go:2:4: r: error\n"},
+		{"multibyte prefix", "日本 error", []span{{start: 7, end: 12, color: "r"}}, "x.go:2:8: r: error\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		quickfixRenderer{}.write(&b, newKolorit(), "x.go", 2, tt.text, tt.spans)
+		if b.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+}
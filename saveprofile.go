@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	toml "github.com/pelletier/go-toml"
+)
+
+// options which are not saved with -save-profile
+var unsavedKeys = map[string]bool{"force": true, "d": true}
+
+// tableHeaderRegexp matches header of table or array of tables, and its submatch is name of table
+var tableHeaderRegexp = regexp.MustCompile(`^\s*\[\[?\s*([^\[\]]+?)\s*\]\]?\s*(#.*)?\s*$`)
+
+// commandLineValues returns options given in command line as values of profile
+func (kolorit *kolorit) commandLineValues() map[string]interface{} {
+	values := make(map[string]interface{})
+	for k := range kolorit.cliOptions {
+		if _, ok := colorMap[k]; ok {
+			values[k] = kolorit.regexps[k]
+			continue
+		}
+		if v, ok := kolorit.bgColors[k]; ok {
+			values[k] = v
+			continue
+		}
+		def, ok := findOptDef(k)
+		if !ok || profileIgnoredKeys[k] || unsavedKeys[k] {
+			continue
+		}
+		switch {
+		case def.isBool:
+			values[k] = kolorit.options[k]
+		case def.isString:
+			values[k] = kolorit.strOptions[k]
+		case def.isInt:
+			values[k] = int64(kolorit.intOptions[k])
+		}
+	}
+	return values
+}
+
+// saveProfile writes options given in command line into config file as a section and exits.
+// the file is given with -conf or $HOME/.kolorit.toml, and other contents of it are kept as they are.
+func (kolorit *kolorit) saveProfile(name string) {
+	if reservedSections[name] || strings.Contains(name, ",") {
+		errMessage("cannot save profile as '" + name + "'")
+	}
+	path := kolorit.strOptions["conf"]
+	if path == "" {
+		path = homeDir + ".kolorit.toml"
+	}
+	values := kolorit.commandLineValues()
+	if len(values) == 0 {
+		errMessage("no options to save are given")
+	}
+
+	mode := os.FileMode(0644)
+	content := ""
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode()
+		b, err := ioutil.ReadFile(path)
+		errCheck(err, "cannot read config file: "+path)
+		content = string(b)
+	}
+	var section bytes.Buffer
+	writeTOML(&section, tomlKey(name), values, nil)
+	content, err := replaceSection(content, name, section.String(), kolorit.options["force"])
+	if err != nil {
+		errMessage(err.Error() + " in " + path)
+	}
+	errCheck(ioutil.WriteFile(path, []byte(content), mode), "cannot write config file: "+path)
+	fmt.Println("profile '" + name + "' is saved in " + path)
+	os.Exit(0)
+}
+
+// replaceSection appends section to content of config file, or replaces the existing one when force is true.
+// lines of other sections and comments are kept.
+func replaceSection(content string, name string, section string, force bool) (string, error) {
+	tree, err := toml.Load(content)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse config file: %s", err.Error())
+	}
+	if _, ok := tree.Get(name).(*toml.TomlTree); !ok {
+		if tree.Get(name) != nil {
+			return "", fmt.Errorf("'%s' is not a section", name)
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if content != "" {
+			content += "\n"
+		}
+		return content + section, nil
+	}
+	if !force {
+		return "", fmt.Errorf("profile '%s' already exists. use -force to overwrite it", name)
+	}
+
+	// replace the section and remove its sub tables([NAME.*] and [[NAME.*]]).
+	// a table is lines from its header to the next header except comments and blank lines before it.
+	lines := strings.SplitAfter(content, "\n")
+	start := tree.GetPosition(name).Line - 1
+	if start < 0 || start >= len(lines) {
+		return "", fmt.Errorf("cannot find header of '%s'", name)
+	}
+	removed := make([]bool, len(lines))
+	for i := range lines {
+		if !isTableOf(lines[i], name) {
+			continue
+		}
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if tableHeaderRegexp.MatchString(lines[j]) {
+				end = j
+				break
+			}
+		}
+		for end > i+1 {
+			line := strings.TrimSpace(lines[end-1])
+			if line != "" && !strings.HasPrefix(line, "#") {
+				break
+			}
+			end--
+		}
+		for j := i; j < end; j++ {
+			removed[j] = true
+		}
+		// blank lines before sub table are also removed
+		for j := i - 1; i != start && j >= 0 && strings.TrimSpace(lines[j]) == ""; j-- {
+			removed[j] = true
+		}
+	}
+	if !removed[start] {
+		return "", fmt.Errorf("cannot find header of '%s'", name)
+	}
+
+	var replaced strings.Builder
+	for i, line := range lines {
+		if i == start {
+			replaced.WriteString(section)
+			for j := i + 1; j < len(lines); j++ {
+				if !removed[j] {
+					if strings.TrimSpace(lines[j]) != "" {
+						replaced.WriteString("\n")
+					}
+					break
+				}
+			}
+		}
+		if !removed[i] {
+			replaced.WriteString(line)
+		}
+	}
+	return replaced.String(), nil
+}
+
+// isTableOf returns whether line is header of table name or its sub tables
+func isTableOf(line string, name string) bool {
+	m := tableHeaderRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	for _, key := range []string{name, tomlKey(name)} {
+		if m[1] == key || strings.HasPrefix(m[1], key+".") {
+			return true
+		}
+	}
+	return false
+}
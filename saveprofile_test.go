@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestReplaceSection(t *testing.T) {
+	section := "[log]\nr = 'ERROR'\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"append new section",
+			"[other]\nr = 'a'\n",
+			"[other]\nr = 'a'\n\n[log]\nr = 'ERROR'\n",
+		},
+		{
+			"replace section",
+			"[log]\ny = 'WARN'\n\n# comment of other\n[other]\nr = 'a'\n",
+			"[log]\nr = 'ERROR'\n\n# comment of other\n[other]\nr = 'a'\n",
+		},
+		{
+			"remove sub tables",
+			"[log]\ny = 'WARN'\n\n[log.stderr]\nr = 'x'\n\n[[log.tests]]\ninput = 'a'\n\n[other]\nr = 'a'\n\n[log.rules.r] # rules\nmessage = 'm'\n",
+			"[log]\nr = 'ERROR'\n\n[other]\nr = 'a'\n",
+		},
+		{
+			"keep tables which have the same prefix",
+			"[log]\ny = 'WARN'\n[logs]\nr = 'a'\n",
+			"[log]\nr = 'ERROR'\n\n[logs]\nr = 'a'\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := replaceSection(tt.content, "log", section, true)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := replaceSection("[log]\ny = 'WARN'\n", "log", section, false); err == nil {
+		t.Error("existing section is replaced without force")
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// options which values are completed with names of files
+var fileOptions = map[string]bool{"conf": true, "files-from": true, "newer": true}
+
+// options which values are completed with names of profiles
+var profileOptions = map[string]bool{"use": true}
+
+// names of commands which can be wrapped by shell functions
+var commandNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+-]*$`)
+
+// wrappedCommand is a command which is run by kolorit with profile in shell
+type wrappedCommand struct {
+	name    string
+	profile string
+}
+
+// shellInitCommand prints shell functions which run commands given in match_command of profiles
+// in config files(or given as args) with kolorit, and completion of kolorit for the shell
+func shellInitCommand(args []string) {
+	usage := "kolorit shell-init [-conf FILE] bash|zsh|fish [COMMAND...]"
+	flags, conf := newCommandFlags("shell-init")
+	parseArgs(flags, args)
+	if flags.NArg() == 0 {
+		commandUsage(usage)
+	}
+	shell := flags.Arg(0)
+	writers := map[string]func(io.Writer, []wrappedCommand, string){"bash": writeBashInit, "zsh": writeZshInit, "fish": writeFishInit}
+	write, ok := writers[shell]
+	if !ok {
+		commandUsage(usage)
+	}
+
+	config, err := readConfig(*conf)
+	if err != nil {
+		errMessage(err.Error())
+	}
+	commands, err := wrappedCommands(config, flags.Args()[1:])
+	if err != nil {
+		errMessage(err.Error())
+	}
+	kolorit := "command kolorit"
+	if *conf != "" {
+		kolorit += " -conf " + shellQuote(*conf)
+	}
+	write(os.Stdout, commands, kolorit)
+}
+
+// wrappedCommands returns commands in match_command of profiles. only given names are returned when names are given.
+// commands of built-in profiles are returned only when they are given or auto-builtin is set in [default] of config files.
+func wrappedCommands(config *config, names []string) ([]wrappedCommand, error) {
+	builtin := len(names) > 0
+	if !builtin {
+		p, err := config.effectiveProfile(nil)
+		if err != nil {
+			return nil, err
+		}
+		builtin, _ = p.values["auto-builtin"].(bool)
+	}
+	autoProfiles, err := config.autoProfiles(builtin)
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[string]string)
+	for _, a := range autoProfiles {
+		for _, c := range a.commands {
+			if _, ok := profiles[c]; !ok && commandNameRegexp.MatchString(c) {
+				profiles[c] = a.name
+			}
+		}
+	}
+	if len(names) == 0 {
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	commands := make([]wrappedCommand, 0, len(names))
+	for _, name := range names {
+		p, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("no profile has '%s' in match_command", name)
+		}
+		commands = append(commands, wrappedCommand{name: name, profile: p})
+	}
+	return commands, nil
+}
+
+// shellQuote quotes string with ' for shells
+func shellQuote(s string) string {
+	if commandNameRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// completion candidates of options of kolorit
+type completion struct {
+	// all options
+	options []string
+	// help of each option
+	helps map[string]string
+	// options which take value and not completed
+	valueOptions []string
+	colorOptions []string
+	fileOptions  []string
+	// options which take profile names
+	profileOptions []string
+	// fixed values of options
+	values     map[string][]string
+	colorNames []string
+	commands   []string
+}
+
+func newCompletion() completion {
+	c := completion{helps: make(map[string]string), values: make(map[string][]string)}
+	for _, o := range opt {
+		c.options = append(c.options, o.k)
+		c.helps[o.k] = o.help
+		switch {
+		case o.isBool:
+		case fileOptions[o.k]:
+			c.fileOptions = append(c.fileOptions, o.k)
+		case profileOptions[o.k]:
+			c.profileOptions = append(c.profileOptions, o.k)
+		case o.k == "stderr-color":
+			c.colorOptions = append(c.colorOptions, o.k)
+		case o.k == "format":
+			c.values[o.k] = strings.Split(formatNames(), ", ")
+		case o.k == "sort" || o.k == "sortr":
+			c.values[o.k] = []string{"path", "name", "size", "mtime", "none"}
+		default:
+			c.valueOptions = append(c.valueOptions, o.k)
+		}
+	}
+	for _, color := range colorArray {
+		c.options = append(c.options, color.s, "b"+color.s)
+		c.helps[color.s] = "regexp to be " + color.l
+		c.helps["b"+color.s] = "background color of " + color.l
+		c.valueOptions = append(c.valueOptions, color.s)
+		c.colorOptions = append(c.colorOptions, "b"+color.s)
+		c.colorNames = append(c.colorNames, color.l)
+	}
+	for name := range commands {
+		c.commands = append(c.commands, name)
+	}
+	sort.Strings(c.commands)
+	return c
+}
+
+// dashed returns options with "-" to be used as patterns of case
+func dashed(options []string) string {
+	patterns := make([]string, 0, len(options)*2)
+	for _, o := range options {
+		patterns = append(patterns, "-"+o, "--"+o)
+	}
+	return strings.Join(patterns, "|")
+}
+
+func quotedWords(words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		quoted = append(quoted, shellQuote(w))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func writeBashInit(w io.Writer, commands []wrappedCommand, kolorit string) {
+	fmt.Fprintln(w, "# kolorit shell integration for bash. add the following line to ~/.bashrc")
+	fmt.Fprintln(w, `#   eval "$(kolorit shell-init bash)"`)
+	writePosixFunctions(w, commands, kolorit)
+
+	c := newCompletion()
+	options := make([]string, 0, len(c.options))
+	for _, o := range c.options {
+		options = append(options, "-"+o)
+	}
+	fmt.Fprintln(w, "\n_kolorit() {")
+	fmt.Fprintln(w, `  local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} IFS=$'\n'`)
+	fmt.Fprintln(w, "  if [ \"$COMP_CWORD\" -eq 1 ] && [[ $cur != -* ]]; then")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W '%s' -- \"$cur\") $(compgen -f -- \"$cur\"))\n", strings.Join(c.commands, "\n"))
+	fmt.Fprintln(w, "    return")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  case $prev in")
+	fmt.Fprintf(w, "    %s)\n      COMPREPLY=($(%s profiles list 2>/dev/null | cut -d' ' -f1 | grep -- \"^$cur\"))\n      return ;;\n", dashed(c.profileOptions), kolorit)
+	fmt.Fprintf(w, "    %s)\n      COMPREPLY=($(compgen -W '%s' -- \"$cur\"))\n      COMPREPLY=(\"${COMPREPLY[@]// /\\\\ }\")\n      return ;;\n", dashed(c.colorOptions), strings.Join(c.colorNames, "\n"))
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "    %s)\n      COMPREPLY=($(compgen -W '%s' -- \"$cur\"))\n      return ;;\n", dashed([]string{k}), strings.Join(c.values[k], "\n"))
+	}
+	fmt.Fprintf(w, "    %s)\n      COMPREPLY=($(compgen -f -- \"$cur\"))\n      return ;;\n", dashed(c.fileOptions))
+	fmt.Fprintf(w, "    %s)\n      return ;;\n", dashed(c.valueOptions))
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "  if [[ $cur == -* ]]; then")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W '%s' -- \"$cur\"))\n", strings.Join(options, "\n"))
+	fmt.Fprintln(w, "  else")
+	fmt.Fprintln(w, "    COMPREPLY=($(compgen -f -- \"$cur\"))")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _kolorit kolorit")
+}
+
+func writeZshInit(w io.Writer, commands []wrappedCommand, kolorit string) {
+	fmt.Fprintln(w, "# kolorit shell integration for zsh. add the following line to ~/.zshrc after compinit")
+	fmt.Fprintln(w, `#   eval "$(kolorit shell-init zsh)"`)
+	writePosixFunctions(w, commands, kolorit)
+
+	c := newCompletion()
+	fmt.Fprintln(w, "\n_kolorit() {")
+	fmt.Fprintln(w, "  local prev=${words[CURRENT-1]}")
+	fmt.Fprintln(w, "  if (( CURRENT == 2 )) && [[ $PREFIX != -* ]]; then")
+	fmt.Fprintf(w, "    compadd -- %s\n", quotedWords(c.commands))
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "  case $prev in")
+	fmt.Fprintf(w, "    %s)\n      compadd -- ${(f)\"$(%s profiles list 2>/dev/null | cut -d' ' -f1)\"}\n      return ;;\n", dashed(c.profileOptions), kolorit)
+	fmt.Fprintf(w, "    %s)\n      compadd -- %s\n      return ;;\n", dashed(c.colorOptions), quotedWords(c.colorNames))
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "    %s)\n      compadd -- %s\n      return ;;\n", dashed([]string{k}), quotedWords(c.values[k]))
+	}
+	fmt.Fprintf(w, "    %s)\n      _files\n      return ;;\n", dashed(c.fileOptions))
+	fmt.Fprintf(w, "    %s)\n      return ;;\n", dashed(c.valueOptions))
+	fmt.Fprintln(w, "  esac")
+	fmt.Fprintln(w, "  if [[ $PREFIX == -* ]]; then")
+	fmt.Fprintln(w, "    local -a options")
+	fmt.Fprintln(w, "    options=(")
+	for _, o := range c.options {
+		fmt.Fprintf(w, "      %s\n", shellQuote("-"+o+":"+strings.Replace(c.helps[o], ":", `\:`, -1)))
+	}
+	fmt.Fprintln(w, "    )")
+	fmt.Fprintln(w, "    _describe option options")
+	fmt.Fprintln(w, "  else")
+	fmt.Fprintln(w, "    _files")
+	fmt.Fprintln(w, "  fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "(( $+functions[compdef] )) && compdef _kolorit kolorit")
+}
+
+// writePosixFunctions writes functions for bash and zsh which run commands with kolorit when stdout is a terminal
+func writePosixFunctions(w io.Writer, commands []wrappedCommand, kolorit string) {
+	for _, c := range commands {
+		fmt.Fprintf(w, "\n%s() {\n", c.name)
+		fmt.Fprintf(w, "  if [ -t 1 ]; then\n    %s -use %s -run -- %s \"$@\"\n", kolorit, shellQuote(c.profile), c.name)
+		fmt.Fprintf(w, "  else\n    command %s \"$@\"\n  fi\n}\n", c.name)
+	}
+}
+
+func writeFishInit(w io.Writer, commands []wrappedCommand, kolorit string) {
+	fmt.Fprintln(w, "# kolorit shell integration for fish. add the following line to ~/.config/fish/config.fish")
+	fmt.Fprintln(w, "#   kolorit shell-init fish | source")
+	for _, c := range commands {
+		fmt.Fprintf(w, "\nfunction %s --wraps %s --description %s\n", c.name, c.name, fishQuote(c.name+" colored by kolorit"))
+		fmt.Fprintf(w, "    if isatty stdout\n        %s -use %s -run -- %s $argv\n", kolorit, fishQuote(c.profile), c.name)
+		fmt.Fprintf(w, "    else\n        command %s $argv\n    end\nend\n", c.name)
+	}
+
+	c := newCompletion()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "complete -c kolorit -e")
+	fmt.Fprintf(w, "complete -c kolorit -n __fish_is_first_arg -f -a %s\n", fishQuote(strings.Join(c.commands, " ")))
+	takesValue := make(map[string]string)
+	for _, o := range c.profileOptions {
+		takesValue[o] = fmt.Sprintf("-x -a '(%s profiles list 2>/dev/null | string split -f1 \" \")'", kolorit)
+	}
+	for _, o := range c.colorOptions {
+		takesValue[o] = "-x -a " + fishQuote(fishWords(c.colorNames))
+	}
+	for k, values := range c.values {
+		takesValue[k] = "-x -a " + fishQuote(strings.Join(values, " "))
+	}
+	for _, o := range c.fileOptions {
+		takesValue[o] = "-r -F"
+	}
+	for _, o := range c.valueOptions {
+		takesValue[o] = "-x"
+	}
+	for _, o := range c.options {
+		line := "complete -c kolorit -o " + fishQuote(o)
+		if v, ok := takesValue[o]; ok {
+			line += " " + v
+		}
+		fmt.Fprintln(w, line+" -d "+fishQuote(c.helps[o]))
+	}
+}
+
+// fishQuote quotes string with ' for fish
+func fishQuote(s string) string {
+	if commandNameRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func fishWords(words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		quoted = append(quoted, fishQuote(w))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
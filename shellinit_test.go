@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	toml "github.com/pelletier/go-toml"
+)
+
+func TestWrappedCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		user  string
+		names []string
+		// only these commands are checked when it is given
+		only []string
+		want []wrappedCommand
+	}{
+		{
+			"commands of config files",
+			"[gradle]\nmatch_command = ['gradle']\nr = 'FAIL'\n",
+			nil,
+			nil,
+			[]wrappedCommand{{name: "gradle", profile: "gradle"}},
+		},
+		{
+			"built-in profile which is overridden",
+			"[make]\nmatch_command = ['make', 'gmake']\nr = 'Error'\n",
+			nil,
+			nil,
+			[]wrappedCommand{{name: "gmake", profile: "make"}, {name: "make", profile: "make"}},
+		},
+		{
+			"built-in profiles with auto-builtin",
+			"[default]\nauto-builtin = true\n",
+			nil,
+			[]string{"df", "dig", "make"},
+			[]wrappedCommand{{name: "df", profile: "df"}, {name: "dig", profile: "dig"}, {name: "make", profile: "make"}},
+		},
+		{
+			"built-in profile given as args",
+			"",
+			[]string{"make"},
+			nil,
+			[]wrappedCommand{{name: "make", profile: "make"}},
+		},
+	}
+	for _, tt := range tests {
+		tree, err := toml.Load(tt.user)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		c := &config{files: []*configFile{builtinConfigFile(), {path: "config.toml", tree: tree}}}
+		got, err := wrappedCommands(c, tt.names)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if tt.only != nil {
+			got = filterCommands(got, tt.only...)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func filterCommands(commands []wrappedCommand, names ...string) []wrappedCommand {
+	filtered := make([]wrappedCommand, 0)
+	for _, c := range commands {
+		for _, name := range names {
+			if c.name == name {
+				filtered = append(filtered, c)
+			}
+		}
+	}
+	return filtered
+}
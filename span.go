@@ -0,0 +1,93 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/ktat/go-ansistrings"
+)
+
+// span is a part of text to be colored. start and end are byte offsets of text.
+type span struct {
+	start int
+	end   int
+	// short name of color(e.g. "r", "lb")
+	color string
+}
+
+// findSpans returns spans of text matched with re and number of kinds of matched colors.
+// when regexp of color has parentheses, only the parts matched with them are colored.
+func findSpans(re *regexp.Regexp, text string) ([]span, int) {
+	spans := make([]span, 0)
+	matched := make(map[string]bool)
+	names := re.SubexpNames()
+	for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+		color := ""
+		groups := make([]span, 0)
+		for i := 1; i < len(names); i++ {
+			if match[i*2] == -1 {
+				continue
+			}
+			if c, ok := colorOfGroup[names[i]]; ok {
+				color = c
+				matched[color] = true
+				groups = append(groups, span{start: match[i*2], end: match[i*2+1], color: color})
+			} else if color != "" {
+				groups = append(groups, span{start: match[i*2], end: match[i*2+1], color: color})
+			}
+		}
+		if len(groups) > 1 { // if parenthese exists in regexp, ignore first match which matches whole string
+			groups = groups[1:]
+		}
+		for _, s := range groups {
+			if s.start < s.end {
+				spans = append(spans, s)
+			}
+		}
+	}
+	return overlapRemoved(spans), len(matched)
+}
+
+// overlapRemoved sorts spans and removes spans which overlap earlier ones
+func overlapRemoved(spans []span) []span {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	result := spans[:0]
+	end := 0
+	for _, s := range spans {
+		if s.start >= end {
+			result = append(result, s)
+			end = s.end
+		}
+	}
+	return result
+}
+
+// renderANSI returns text which spans are colored with ANSI escape sequences.
+// colors of input are kept under colors of spans, and restored after spans.
+func (kolorit *kolorit) renderANSI(text string, spans []span) string {
+	rendered := ""
+	last := 0
+	var state sgrState
+	for _, s := range spans {
+		state = state.applyAll(text[last:s.start])
+		var color ansistrings.ANSIString
+		if kolorit.options["B"] {
+			color.Bold()
+		}
+		if kolorit.options["I"] {
+			color.Inverted()
+		}
+		if kolorit.options["U"] {
+			color.UnderLine()
+		}
+		if v, ok := kolorit.bg[s.color]; ok {
+			color.BgColor(v)
+		}
+		n, _ := ansistrings.ColorNumFromName(colorMap[s.color])
+		color.Color(n)
+		color.Str = text[s.start:s.end]
+		rendered += text[last:s.start] + color.String() + state.String()
+		last = s.end
+	}
+	return rendered + text[last:]
+}
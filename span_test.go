@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFindSpans(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		spans   []span
+		kinds   int
+	}{
+		{"two colors", "(?P<red>error)|(?P<yellow>warn)", "warn error warn", []span{{0, 4, "y"}, {5, 10, "r"}, {11, 15, "y"}}, 2},
+		{"no match", "(?P<red>error)", "ok", []span{}, 0},
+		{"parentheses color only groups", "(?P<red>id=(\\d+) name=(\\w+))", "id=12 name=foo", []span{{3, 5, "r"}, {11, 14, "r"}}, 1},
+		{"multibyte", "(?P<red>é+)", "aééb", []span{{1, 5, "r"}}, 1},
+	}
+	for _, tt := range tests {
+		spans, kinds := findSpans(regexp.MustCompile(tt.pattern), tt.text)
+		if !reflect.DeepEqual(spans, tt.spans) || kinds != tt.kinds {
+			t.Errorf("%s: got %v %d, want %v %d", tt.name, spans, kinds, tt.spans, tt.kinds)
+		}
+	}
+}
+
+func TestOverlapRemoved(t *testing.T) {
+	spans := overlapRemoved([]span{{5, 8, "y"}, {0, 3, "r"}, {2, 6, "g"}, {8, 9, "b"}})
+	want := []span{{0, 3, "r"}, {5, 8, "y"}, {8, 9, "b"}}
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("got %v, want %v", spans, want)
+	}
+}
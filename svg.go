@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// size of SVG
+const (
+	svgFontSize   = 14
+	svgCharWidth  = 8.4
+	svgLineHeight = 18
+	svgPadding    = 10
+)
+
+// svgRenderer writes colored text as monospaced SVG image after reading all inputs
+type svgRenderer struct {
+	lines [][]segment
+}
+
+func (s *svgRenderer) begin(w io.Writer) {}
+
+func (s *svgRenderer) write(w io.Writer, kolorit *kolorit, name string, ln int, text string, spans []span) {
+	// content read with -s has newlines
+	current := make([]segment, 0)
+	for _, seg := range kolorit.segmentsOf(name, ln, text, spans) {
+		parts := strings.Split(seg.text, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				s.lines = append(s.lines, current)
+				current = make([]segment, 0)
+			}
+			if part != "" {
+				current = append(current, segment{text: strings.Replace(part, "\t", "        ", -1), style: seg.style, color: seg.color})
+			}
+		}
+	}
+	s.lines = append(s.lines, current)
+}
+
+func (s *svgRenderer) end(w io.Writer) {
+	columns := 0
+	for _, line := range s.lines {
+		n := 0
+		for _, seg := range line {
+			n += utf8.RuneCountInString(seg.text)
+		}
+		if n > columns {
+			columns = n
+		}
+	}
+	width := float64(columns)*svgCharWidth + svgPadding*2
+	height := len(s.lines)*svgLineHeight + svgPadding*2
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%d" font-family="monospace" font-size="%d">`+"\n", width, height, svgFontSize)
+	fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", rgbBackground)
+	for i, line := range s.lines {
+		top := svgPadding + i*svgLineHeight
+		var texts strings.Builder
+		column := 0
+		for _, seg := range line {
+			fill, attrs := rgbForeground, ""
+			if seg.color != "" {
+				fill = rgbColors[seg.color]
+			}
+			if st := seg.style; st != nil {
+				fill = rgbColors[colorMap[st.color]]
+				bg := ""
+				if st.bg != "" {
+					bg = rgbColors[colorMap[st.bg]]
+				}
+				if st.inverse {
+					fill, bg = rgbBackground, fill
+				}
+				if bg != "" {
+					fmt.Fprintf(w, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"/>`+"\n",
+						svgPadding+float64(column)*svgCharWidth, top, float64(utf8.RuneCountInString(seg.text))*svgCharWidth, svgLineHeight, bg)
+				}
+				if st.bold {
+					attrs += ` font-weight="bold"`
+				}
+				if st.underline {
+					attrs += ` text-decoration="underline"`
+				}
+			}
+			fmt.Fprintf(&texts, `<tspan x="%.1f" fill="%s"%s>%s</tspan>`, svgPadding+float64(column)*svgCharWidth, fill, attrs, html.EscapeString(seg.text))
+			column += utf8.RuneCountInString(seg.text)
+		}
+		if texts.Len() > 0 {
+			fmt.Fprintf(w, `<text y="%d" xml:space="preserve">%s</text>`+"\n", top+svgFontSize, texts.String())
+		}
+	}
+	fmt.Fprintln(w, "</svg>")
+}
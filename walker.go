@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type foundFile struct {
+	path string
+	info os.FileInfo
+}
+
+var sortKeys = map[string]func(a, b foundFile) bool{
+	"path":  func(a, b foundFile) bool { return a.path < b.path },
+	"name":  func(a, b foundFile) bool { return filepath.Base(a.path) < filepath.Base(b.path) },
+	"size":  func(a, b foundFile) bool { return a.info.Size() < b.info.Size() },
+	"mtime": func(a, b foundFile) bool { return a.info.ModTime().Before(b.info.ModTime()) },
+}
+
+var sizeUnits = map[byte]int64{'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+
+// parseWalkOptions parses options to filter and sort files found in directories
+func (kolorit *kolorit) parseWalkOptions() {
+	var err error
+	if s := kolorit.strOptions["max-filesize"]; s != "" {
+		kolorit.maxFileSize, err = parseSize(s)
+		errCheck(err, "wrong size: "+s)
+	}
+	if f := kolorit.strOptions["newer"]; f != "" {
+		fi, err := os.Stat(f)
+		errCheck(err, "error on stat file: "+f)
+		kolorit.newerThan = fi.ModTime()
+	}
+	if s := kolorit.strOptions["changed-within"]; s != "" {
+		d, err := parseAge(s)
+		errCheck(err, "wrong duration: "+s)
+		if t := time.Now().Add(-d); t.After(kolorit.newerThan) {
+			kolorit.newerThan = t
+		}
+	}
+	kolorit.sortKey = "path"
+	for _, k := range []string{"sort", "sortr"} {
+		if v := kolorit.strOptions[k]; v != "" {
+			if _, ok := sortKeys[v]; !ok && v != "none" {
+				errMessage("unknown sort key: " + v + " (path, name, size, mtime or none)")
+			}
+			kolorit.sortKey = v
+			kolorit.sortReverse = k == "sortr"
+		}
+	}
+}
+
+// parseSize parses size like 512, 100K, 10M or 1G
+func parseSize(s string) (int64, error) {
+	unit := int64(1)
+	if u, ok := sizeUnits[strings.ToUpper(s[len(s)-1:])[0]]; ok {
+		unit = u
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n * unit, err
+}
+
+// parseAge parses duration like 30m or 2h and also accepts days(7d) and weeks(2w)
+func parseAge(s string) (time.Duration, error) {
+	days := map[byte]float64{'d': 1, 'w': 7}
+	if n, ok := days[s[len(s)-1]]; ok {
+		f, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		return time.Duration(f * n * float64(24*time.Hour)), err
+	}
+	return time.ParseDuration(s)
+}
+
+func (kolorit *kolorit) seekDir(files *[]string, dirName string) {
+	if isDebug {
+		log.Println("### seekDir")
+		log.Println("File Name:" + kolorit.fileName)
+		log.Println("Dir Name:" + dirName)
+	}
+	found := make([]foundFile, 0)
+	kolorit.walkDir(dirName, 0, nil, &found)
+	if kolorit.sortKey != "none" {
+		less := sortKeys[kolorit.sortKey]
+		sort.SliceStable(found, func(i, j int) bool {
+			if kolorit.sortReverse {
+				return less(found[j], found[i])
+			}
+			return less(found[i], found[j])
+		})
+	}
+	for _, f := range found {
+		if isDebug {
+			log.Println("File Full Name: " + f.path)
+		}
+		*files = append(*files, f.path)
+	}
+}
+
+// walkDir collects files under root. depth is the depth of root from the directory given by user.
+// ancestors are real paths of directories which have symbolic links followed to reach root.
+func (kolorit *kolorit) walkDir(root string, depth int, ancestors []string, found *[]foundFile) {
+	realRoot, err := realPath(root)
+	if err != nil {
+		log.Println(err.Error() + " :error on reading dir: " + root)
+		return
+	}
+
+	walkRoot := root
+	if fi, err := os.Lstat(root); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		// WalkDir doesn't follow root if it is a symbolic link
+		walkRoot = root + string(os.PathSeparator) + "."
+	}
+	maxDepth := kolorit.intOptions["max-depth"]
+	if !kolorit.isRecursive {
+		maxDepth = 1
+	}
+
+	filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Println(err.Error() + " :error on reading dir: " + path)
+			return nil
+		}
+		rel, _ := filepath.Rel(walkRoot, path)
+		if rel == "." {
+			return nil
+		}
+		fileDepth := depth + strings.Count(rel, string(os.PathSeparator)) + 1
+		name := d.Name()
+
+		var fi os.FileInfo
+		if d.Type()&fs.ModeSymlink != 0 {
+			if fi, err = os.Stat(path); err != nil {
+				log.Println(err.Error() + " :error on stat file: " + path)
+				return nil
+			}
+			if fi.IsDir() {
+				if kolorit.options["L"] && kolorit.isRecursive && !kolorit.isIgnoreDirs(name) && (maxDepth == 0 || fileDepth < maxDepth) {
+					chain := append(ancestors[:len(ancestors):len(ancestors)], filepath.Join(realRoot, filepath.Dir(rel)))
+					if isLoop(path, chain) {
+						log.Println("symbolic link loop is found: " + path)
+						return nil
+					}
+					if isDebug {
+						log.Println("Seek Dir: " + path)
+					}
+					kolorit.walkDir(path, fileDepth, chain, found)
+				}
+				return nil
+			}
+		} else if d.IsDir() {
+			if !kolorit.isRecursive || kolorit.isIgnoreDirs(name) || (maxDepth > 0 && fileDepth >= maxDepth) {
+				return filepath.SkipDir
+			}
+			if isDebug {
+				log.Println("Seek Dir: " + path)
+			}
+			return nil
+		}
+		if fi == nil && !d.Type().IsRegular() {
+			return nil
+		}
+
+		if kolorit.isIgnoreFile(name) || (kolorit.fileName != "" && !kolorit.checkFileName(path)) {
+			return nil
+		}
+		if fi == nil {
+			if fi, err = d.Info(); err != nil {
+				log.Println(err.Error() + " :error on stat file: " + path)
+				return nil
+			}
+		}
+		if !kolorit.matchFileInfo(fi) {
+			return nil
+		}
+		*found = append(*found, foundFile{path: path, info: fi})
+		return nil
+	})
+}
+
+func realPath(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(real)
+}
+
+// isLoop returns true when symbolic link points to any of directories or their parents
+func isLoop(link string, dirs []string) bool {
+	target, err := realPath(link)
+	if err != nil {
+		return false
+	}
+	for _, dir := range dirs {
+		if dir == target || strings.HasPrefix(dir, target+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFileInfo checks size and modified time of file
+func (kolorit *kolorit) matchFileInfo(fi os.FileInfo) bool {
+	if kolorit.maxFileSize > 0 && fi.Size() > kolorit.maxFileSize {
+		return false
+	}
+	if !kolorit.newerThan.IsZero() && !fi.ModTime().After(kolorit.newerThan) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ktat/go-ansistrings"
+)
+
+// signals which are forwarded to wrapped command
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// isKeyboardSignal returns whether sig is sent by terminal to all processes in foreground(e.g. by Ctrl-C)
+func isKeyboardSignal(sig os.Signal) bool {
+	return sig == os.Interrupt || sig == syscall.SIGQUIT
+}
+
+// output is colored text from stdout or stderr of wrapped command
+type output struct {
+	text     string
+	isStderr bool
+}
+
+// isFile returns whether name is an input file. directory is regarded as a file only with -R
+// not to read a directory which has the same name as a command(e.g. "make").
+func isFile(name string, isRecursive bool) bool {
+	if name == stdinName {
+		return true
+	}
+	fi, err := os.Stat(name)
+	return err == nil && (!fi.IsDir() || isRecursive)
+}
+
+// setWrapped sets command to run. the command is removed from files, and files cannot be given with it.
+func (kolorit *kolorit) setWrapped(command []string) {
+	kolorit.files = kolorit.files[:len(kolorit.files)-len(command)]
+	if len(kolorit.files) > 0 || kolorit.isRecursive || kolorit.strOptions["f"] != "" || kolorit.strOptions["files-from"] != "" {
+		errMessage("cannot give files with command: " + command[0])
+	}
+	if kolorit.strOptions["format"] != "ansi" {
+		errMessage("-format cannot be used with command: " + command[0])
+	}
+	kolorit.wrapped = command
+	if kolorit.strOptions["command"] == "" {
+		kolorit.strOptions["command"] = command[0]
+	}
+}
+
+// runWrapped runs command and colors its stdout and stderr, and exits with exit code of the command.
+// lines are written in order of being read, and signals given to kolorit are forwarded to the command.
+func (kolorit *kolorit) runWrapped() {
+	out, errOut := kolorit.forWrapped()
+
+	cmd := exec.Command(kolorit.wrapped[0], kolorit.wrapped[1:]...)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, forwardedSignals...)
+	stdout, stderr, restore, err := kolorit.startCommand(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		os.Exit(127)
+	}
+	// command which is not run with pseudo terminals is in the same process group as kolorit,
+	// and it receives signals from keyboard directly
+	sharesGroup := cmd.SysProcAttr == nil
+	go func() {
+		for sig := range signals {
+			if sharesGroup && isKeyboardSignal(sig) {
+				continue
+			}
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	outputs := make(chan output)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		out.readStream(stdout, false, outputs)
+		wg.Done()
+	}()
+	go func() {
+		errOut.readStream(stderr, true, outputs)
+		wg.Done()
+	}()
+	go func() {
+		wg.Wait()
+		close(outputs)
+	}()
+	for o := range outputs {
+		if o.isStderr {
+			fmt.Fprint(os.Stderr, o.text)
+		} else {
+			fmt.Fprint(os.Stdout, o.text)
+		}
+	}
+
+	err = cmd.Wait()
+	signal.Stop(signals)
+	restore()
+	os.Exit(exitCode(err))
+}
+
+// startWithPipes starts command which stdout and stderr are pipes
+func startWithPipes(cmd *exec.Cmd) (io.Reader, io.Reader, func(), error) {
+	cmd.Stdin = os.Stdin
+	stdout, err := cmd.StdoutPipe()
+	errCheck(err, "error on creating pipe of stdout")
+	stderr, err := cmd.StderrPipe()
+	errCheck(err, "error on creating pipe of stderr")
+	return stdout, stderr, func() {}, cmd.Start()
+}
+
+// forWrapped returns kolorits for stdout and stderr of wrapped command.
+// profile is selected by match_command, and 'stderr' table of profile has options for stderr instead of profile.
+func (kolorit *kolorit) forWrapped() (*kolorit, *kolorit) {
+	out := kolorit
+	uses := splitNames(kolorit.strOptions["use"])
+	if len(kolorit.autoProfiles) > 0 {
+		if selected := kolorit.matchProfile(stdinName, nil); selected != "" {
+			if isDebug {
+				log.Println("Selected Profile: " + selected + " for " + kolorit.wrapped[0])
+			}
+			out = kolorit.withSelected(selected)
+			uses = []string{selected}
+		}
+	}
+
+	errOut := *out
+	if kolorit.config != nil {
+		p, err := kolorit.config.effectiveProfile(uses)
+		if err != nil {
+			errMessage(err.Error())
+		}
+		if table, ok := p.values["stderr"].(map[string]interface{}); ok {
+			sp := newProfile()
+			for k, v := range table {
+				sp.values[k] = v
+				sp.from[k] = p.from["stderr"] + ".stderr"
+				sp.sources[k] = p.sources["stderr"]
+			}
+			errOut = *kolorit.withValues(sp)
+		}
+	}
+	if color := errOut.strOptions["stderr-color"]; color != "none" {
+		if _, err := ansistrings.ColorNumFromName(color); err != nil {
+			errMessage("unknown color name in -stderr-color: " + color)
+		}
+		errOut.tint = color
+	}
+	out.sanitizing = out.sanitizes(false)
+	errOut.sanitizing = errOut.sanitizes(false)
+	return out, &errOut
+}
+
+// readStream colors output of wrapped command line by line, or whole output with -s, and sends it to outputs.
+// a line which is not terminated for a while(e.g. prompt) is colored and sent without waiting for the rest.
+func (kolorit *kolorit) readStream(r io.Reader, isStderr bool, outputs chan<- output) {
+	name := "stdout of " + kolorit.wrapped[0]
+	if isStderr {
+		name = "stderr of " + kolorit.wrapped[0]
+	}
+	if kolorit.asSingle {
+		whole, err := ioutil.ReadAll(r)
+		if err != nil {
+			log.Println(err.Error() + " :error on reading " + name)
+		}
+		colored, _, err := kolorit.coloringText(kolorit.re, kolorit.reErase, kolorit.sanitized(string(whole)))
+		if err != nil {
+			log.Println(err.Error() + " : " + name)
+			colored = string(whole)
+		}
+		outputs <- output{text: kolorit.tinted(colored), isStderr: isStderr}
+		return
+	}
+
+	chunks := make(chan string)
+	go readChunks(r, name, chunks)
+	pending := ""
+	for {
+		var timeout <-chan time.Time
+		if pending != "" {
+			timeout = time.After(pendingDelay)
+		}
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				if pending != "" {
+					kolorit.sendLine(pending, true, isStderr, outputs)
+				}
+				return
+			}
+			pending += chunk
+			for n := lineEnd(pending); n > 0; n = lineEnd(pending) {
+				kolorit.sendLine(pending[:n], false, isStderr, outputs)
+				pending = pending[n:]
+			}
+		case <-timeout:
+			kolorit.sendLine(pending, true, isStderr, outputs)
+			pending = ""
+		}
+	}
+}
+
+// time to wait for the rest of line before coloring it
+const pendingDelay = 50 * time.Millisecond
+
+// readChunks sends content read from r to chunks until the end of it
+func readChunks(r io.Reader, name string, chunks chan<- string) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			chunks <- string(buf[:n])
+		}
+		if err != nil {
+			// reading pseudo terminal fails with EIO after the command exits
+			if err != io.EOF && !errors.Is(err, syscall.EIO) {
+				log.Println(err.Error() + " :error on reading " + name)
+			}
+			close(chunks)
+			return
+		}
+	}
+}
+
+// lineEnd returns length of the first line including "\n", or "\r" which is not followed by "\n"(e.g. progress bar).
+// it returns -1 when line is not terminated yet.
+func lineEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\n':
+			return i + 1
+		case s[i] == '\r' && i+1 < len(s) && s[i+1] != '\n':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// sendLine colors line and sends it to outputs. partial line is always sent even with -grep.
+func (kolorit *kolorit) sendLine(line string, partial bool, isStderr bool, outputs chan<- output) {
+	content := strings.TrimRight(line, "\r\n")
+	text, spans, show, err := kolorit.matchLine(content)
+	colored := kolorit.renderANSI(text, spans)
+	if err != nil {
+		// output is passed through not to lose it
+		colored, show = content, true
+	}
+	if show || partial {
+		outputs <- output{text: kolorit.tinted(colored) + line[len(content):], isStderr: isStderr}
+	}
+}
+
+// tinted colors text which is not colored yet with color of tint
+func (kolorit *kolorit) tinted(colored string) string {
+	if kolorit.tint == "" || colored == "" {
+		return colored
+	}
+	n, _ := ansistrings.ColorNumFromName(kolorit.tint)
+	color := ansistrings.ANSIString{Str: "\x00"}
+	color.Color(n)
+	seq := strings.SplitN(color.String(), "\x00", 2)
+	start, reset := seq[0], seq[1]
+	tinted := start + strings.Replace(colored, reset, reset+start, -1) + reset
+	return strings.Replace(tinted, start+reset, "", -1)
+}
+
+// exitCode returns exit code of wrapped command. it is 128 + signal number when the command is killed by signal.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			if status.Signaled() {
+				return 128 + int(status.Signal())
+			}
+			return status.ExitStatus()
+		}
+	}
+	log.Println(err.Error())
+	return 1
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadStreamSanitized(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]bool
+		input   string
+		want    string
+	}{
+		{
+			"OSC is escaped",
+			map[string]bool{},
+			"a\033]0;pwned\007b\n\033]52;c;ZXZpbA==\033\\\n",
+			"a^[]0;pwned^Gb\n^[]52;c;ZXZpbA==^[\\\n",
+		},
+		{
+			"colors and progress are kept",
+			map[string]bool{},
+			"\033[32mok\033[m\n 50%\033[K\r",
+			"\033[32mok\033[m\n 50%\033[K\r",
+		},
+		{
+			"not sanitized with -nsanitize",
+			map[string]bool{"nsanitize": true},
+			"a\033]0;title\007b\n",
+			"a\033]0;title\007b\n",
+		},
+	}
+	for _, tt := range tests {
+		k := newKolorit()
+		k.options = tt.options
+		k.strOptions["stderr-color"] = "none"
+		k.wrapped = []string{"tail"}
+		k.prepare()
+		out, _ := k.forWrapped()
+
+		outputs := make(chan output)
+		go func() {
+			out.readStream(strings.NewReader(tt.input), false, outputs)
+			close(outputs)
+		}()
+		var got strings.Builder
+		for o := range outputs {
+			got.WriteString(o.text)
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got.String(), tt.want)
+		}
+	}
+}